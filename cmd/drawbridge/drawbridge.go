@@ -22,17 +22,23 @@ var goarch string
 
 func main() {
 
-	config, err := config.Create()
+	//the `--config` flag must be parsed before the app is created, since the config is used to generate the `create` flags.
+	workingDir, _ := os.Getwd()
+	userConfigFilePath, _ := config.UserConfigFilePath(configFlagValue(os.Args[1:]))
+	configFileLayers, err := config.ConfigFileLayers(config.SystemConfigFilePath, configFlagValue(os.Args[1:]), workingDir)
+	if err != nil && isCommand(os.Args[1:], "init") {
+		//the user config file is created by `init`
+		configFileLayers, err = []string{}, nil
+	}
 	if err != nil {
 		fmt.Printf("FATAL: %+v\n", err)
 		os.Exit(1)
 	}
 
-	//we're going to load the config file manually, since we need to validate it.
-	err = config.ReadConfig("~/drawbridge.yaml")          // Find and read the config file
-	if _, ok := err.(errors.ConfigFileMissingError); ok { // Handle errors reading the config file
-		//ignore "could not find config file"
-	} else if err != nil {
+	//we're going to load the config files manually, since we need to validate each layer before merging it.
+	config, err := loadConfig(configFileLayers, os.Args[1:])
+	if err != nil {
+		fmt.Printf("FATAL: %+v\n", err)
 		os.Exit(1)
 	}
 
@@ -66,6 +72,13 @@ OPTIONS:
 				Email: "jason@thesparktree.com",
 			},
 		},
+		Flags: []cli.Flag{
+			// parsed manually (see configFlagValue) before the app is created. Declared here for help & validation.
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: "Path to the user config `file` (defaults to $DRAWBRIDGE_CONFIG, then ~/drawbridge.yaml)",
+			},
+		},
 		Before: func(c *cli.Context) error {
 
 			drawbridge := "github.com/AnalogJ/drawbridge"
@@ -206,7 +219,7 @@ OPTIONS:
 						destServer = ""
 					}
 
-					connectAction := actions.ConnectAction{Config: config}
+					connectAction := actions.ConnectAction{Config: config, Native: c.Bool("native"), SkipPreflight: c.Bool("skip-preflight"), ReloadKey: c.Bool("reload-key")}
 					return connectAction.Start(answerData, destServer)
 				},
 
@@ -215,6 +228,18 @@ OPTIONS:
 						Name:  "dest",
 						Usage: "Specify the `hostname` of the destination/internal server you would like to connect to.",
 					},
+					&cli.BoolFlag{
+						Name:  "native",
+						Usage: "Use the built-in ssh client rather than the ssh binary. Falls back to the ssh binary if the config is not supported.",
+					},
+					&cli.BoolFlag{
+						Name:  "skip-preflight",
+						Usage: "Skip checking that the bastion host is reachable before connecting",
+					},
+					&cli.BoolFlag{
+						Name:  "reload-key",
+						Usage: "Re-add the pem key to the ssh-agent, even if it's already loaded",
+					},
 				},
 			},
 			{
@@ -276,9 +301,132 @@ OPTIONS:
 						}
 					}
 
-					downloadAction := actions.DownloadAction{Config: config}
+					downloadAction := actions.DownloadAction{
+						ConnectAction: actions.ConnectAction{Config: config, SkipPreflight: c.Bool("skip-preflight"), ReloadKey: c.Bool("reload-key")},
+						Config:        config,
+					}
 					return downloadAction.Start(answerData, strRemoteHostname, strRemotePath, strLocalPath)
 				},
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "skip-preflight",
+						Usage: "Skip checking that the bastion host is reachable before downloading",
+					},
+					&cli.BoolFlag{
+						Name:  "reload-key",
+						Usage: "Re-add the pem key to the ssh-agent, even if it's already loaded",
+					},
+				},
+			},
+			{
+				Name:      "upload",
+				Usage:     "Upload a file (or directory) to an internal server using drawbridge managed ssh config, syntax is similar to scp command. ",
+				ArgsUsage: "[config_number] local_filepath destination_hostname:remote_filepath",
+				Action: func(c *cli.Context) error {
+					fmt.Fprintln(c.App.Writer, c.Command.Usage)
+
+					// PARSE ARGS
+					if c.NArg() < 2 || c.NArg() > 3 {
+						return errors.InvalidArgumentsError(fmt.Sprintf("2 or 3 arguments required. %v provided", c.Args().Len()))
+					}
+
+					index := 0
+					strRemoteHostname := ""
+					strRemotePath := ""
+					strLocalPath := ""
+
+					args := c.Args().Slice()
+
+					if c.NArg() == 3 {
+						index, err = utils.StringToInt(c.Args().First())
+						if err != nil {
+							return errors.InvalidArgumentsError("Invalid `config_id`, please specify a number")
+						}
+						args = c.Args().Tail()
+					}
+
+					strLocalPath = args[0]
+
+					remoteParts := strings.Split(args[1], ":")
+					if len(remoteParts) != 2 {
+						return errors.InvalidArgumentsError(fmt.Sprintf("Invalid `destination_hostname:remote path` format: %s", remoteParts))
+					} else {
+						strRemoteHostname = remoteParts[0]
+						strRemotePath = remoteParts[1]
+					}
+
+					// select answer data.
+					projectList, err := project.CreateProjectListFromConfigDir(config)
+					if err != nil {
+						return err
+					}
+
+					var answerData map[string]interface{}
+					if index > 0 {
+
+						answerData, err = projectList.GetIndex(index - 1)
+						if err != nil {
+							return err
+						}
+
+					} else {
+						answerData, err = projectList.Prompt("Enter number of drawbridge config you would like to upload to")
+						if err != nil {
+							return err
+						}
+					}
+
+					uploadAction := actions.UploadAction{ConnectAction: actions.ConnectAction{Config: config}, Config: config}
+					return uploadAction.Start(answerData, strLocalPath, strRemoteHostname, strRemotePath)
+				},
+			},
+			{
+				Name:      "exec",
+				Usage:     "Run a command on multiple internal servers (in parallel) using drawbridge managed ssh config",
+				ArgsUsage: "[config_number] --hosts host1,host2 -- command",
+				Action: func(c *cli.Context) error {
+					fmt.Fprintln(c.App.Writer, c.Command.Usage)
+
+					execArgs, err := parseExecArgs(c.Args().Slice(), c.String("hosts"), c.Int("parallel"))
+					if err != nil {
+						return err
+					}
+
+					// select answer data.
+					projectList, err := project.CreateProjectListFromConfigDir(config)
+					if err != nil {
+						return err
+					}
+
+					var answerData map[string]interface{}
+					if execArgs.index > 0 {
+
+						answerData, err = projectList.GetIndex(execArgs.index - 1)
+						if err != nil {
+							return err
+						}
+
+					} else {
+						answerData, err = projectList.Prompt("Enter number of drawbridge config you would like to run the command through")
+						if err != nil {
+							return err
+						}
+					}
+
+					execAction := actions.ExecAction{ConnectAction: actions.ConnectAction{Config: config}, Config: config}
+					return execAction.Start(answerData, execArgs.hosts, execArgs.command, execArgs.parallel)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "hosts",
+						Usage: "Comma separated list of destination/internal server `hostnames`",
+					},
+					&cli.IntFlag{
+						Name:  "parallel",
+						Usage: "Maximum number of hosts to run the command on concurrently",
+						Value: 5,
+					},
+				},
 			},
 			{
 				Name:      "delete",
@@ -346,21 +494,282 @@ OPTIONS:
 					//TODO: add dry run support
 				},
 			},
+			{
+				Name:  "config",
+				Usage: "Manage the drawbridge configuration",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "sync",
+						Usage: "Fetch the shared config declared in `options.remote_config`, and cache it in the config_dir",
+						Action: func(c *cli.Context) error {
+							configSyncAction := actions.ConfigSyncAction{Config: config}
+							return configSyncAction.Start()
+						},
+					},
+					{
+						Name:  "validate",
+						Usage: "Validate the config files, check references between keys, and dry-render every template",
+						Action: func(c *cli.Context) error {
+							configValidateAction := actions.ConfigValidateAction{Config: config}
+							return configValidateAction.Start(configFileLayers)
+						},
+					},
+					{
+						Name:      "migrate",
+						Usage:     "Rewrite config files that use an older schema version. A backup of each original file is kept",
+						ArgsUsage: "[config_file...]",
+						Action: func(c *cli.Context) error {
+							configFilePaths := configFileLayers
+							if c.NArg() > 0 {
+								configFilePaths = c.Args().Slice()
+							}
+
+							configMigrateAction := actions.ConfigMigrateAction{Config: config}
+							return configMigrateAction.Start(configFilePaths)
+						},
+					},
+				},
+			},
+			{
+				Name:  "hostkeys",
+				Usage: "Manage the host keys recorded in the known_hosts file of a drawbridge managed ssh config",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "list",
+						Usage:     "List the recorded host keys",
+						ArgsUsage: "[config_number]",
+						Action: func(c *cli.Context) error {
+							answerData, err := selectAnswerData(config, c.Args().Get(0), "Enter number of drawbridge config you would like to list host keys for")
+							if err != nil {
+								return err
+							}
+
+							hostKeysAction := actions.HostKeysAction{Config: config}
+							return hostKeysAction.List(answerData)
+						},
+					},
+					{
+						Name:      "forget",
+						Usage:     "Remove the recorded host keys for a host. The new key will be trusted on next connect",
+						ArgsUsage: "[config_number] host[:port]",
+						Action: func(c *cli.Context) error {
+							if c.NArg() < 1 || c.NArg() > 2 {
+								return errors.InvalidArgumentsError(fmt.Sprintf("1 or 2 arguments required. %v provided", c.Args().Len()))
+							}
+
+							strIndex := ""
+							args := c.Args().Slice()
+							if c.NArg() == 2 {
+								strIndex = args[0]
+								args = args[1:]
+							}
+
+							answerData, err := selectAnswerData(config, strIndex, "Enter number of drawbridge config you would like to forget a host key for")
+							if err != nil {
+								return err
+							}
+
+							hostKeysAction := actions.HostKeysAction{Config: config}
+							return hostKeysAction.Forget(answerData, args[0])
+						},
+					},
+					{
+						Name:      "pin",
+						Usage:     "Trust only the provided public key for a host",
+						ArgsUsage: "[config_number] host[:port] public_key_filepath",
+						Action: func(c *cli.Context) error {
+							if c.NArg() < 2 || c.NArg() > 3 {
+								return errors.InvalidArgumentsError(fmt.Sprintf("2 or 3 arguments required. %v provided", c.Args().Len()))
+							}
+
+							strIndex := ""
+							args := c.Args().Slice()
+							if c.NArg() == 3 {
+								strIndex = args[0]
+								args = args[1:]
+							}
+
+							answerData, err := selectAnswerData(config, strIndex, "Enter number of drawbridge config you would like to pin a host key for")
+							if err != nil {
+								return err
+							}
+
+							hostKeysAction := actions.HostKeysAction{Config: config}
+							return hostKeysAction.Pin(answerData, args[0], args[1])
+						},
+					},
+				},
+			},
 			{
 				Name:  "proxy",
 				Usage: "Build/Rebuild a Proxy auto-config (PAC) file to access websites through Drawbridge tunnels",
 				Action: func(c *cli.Context) error {
 					fmt.Fprintln(c.App.Writer, c.Command.Usage)
 
+					proxyAction := actions.ProxyAction{ConnectAction: actions.ConnectAction{Config: config}, Config: config}
+					if c.Bool("serve") {
+						return proxyAction.ServePac(c.String("listen"))
+					}
+
 					projectList, err := project.CreateProjectListFromConfigDir(config)
 					if err != nil {
 						return err
 					}
 					answerDataList := projectList.GetAll()
 
-					proxyAction := actions.ProxyAction{Config: config}
 					return proxyAction.Start(answerDataList, false)
 				},
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "serve",
+						Usage: "Serve the PAC file over http, rather than writing it to disk. Re-rendered whenever a drawbridge config changes.",
+					},
+					&cli.StringFlag{
+						Name:  "listen",
+						Usage: "The `address` the PAC file server listens on",
+						Value: "localhost:8788",
+					},
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:      "serve",
+						Usage:     "Run a local SOCKS5/HTTP proxy that tunnels connections through the bastion of a drawbridge managed ssh config",
+						ArgsUsage: "[config_number]",
+						Action: func(c *cli.Context) error {
+							fmt.Fprintln(c.App.Writer, c.Command.Usage)
+
+							projectList, err := project.CreateProjectListFromConfigDir(config)
+							if err != nil {
+								return err
+							}
+
+							var answerData map[string]interface{}
+							if c.NArg() > 0 {
+
+								index, err := utils.StringToInt(c.Args().Get(0))
+								if err != nil {
+									return err
+								}
+								answerData, err = projectList.GetIndex(index - 1)
+								if err != nil {
+									return err
+								}
+
+							} else {
+								answerData, err = projectList.Prompt("Enter drawbridge config number to proxy through")
+								if err != nil {
+									return err
+								}
+							}
+
+							proxyAction := actions.ProxyAction{ConnectAction: actions.ConnectAction{Config: config}, Config: config}
+							return proxyAction.Serve(answerData)
+						},
+					},
+				},
+			},
+			{
+				Name:  "tunnel",
+				Usage: "Manage background tunnels (bastion forwards) for drawbridge managed ssh configs",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "up",
+						Usage:     "Start a background daemon that keeps tunnels open, reconnecting automatically",
+						ArgsUsage: "[config_number...]",
+						Action: func(c *cli.Context) error {
+							fmt.Fprintln(c.App.Writer, c.Command.Usage)
+
+							projectList, err := project.CreateProjectListFromConfigDir(config)
+							if err != nil {
+								return err
+							}
+
+							answerDataList := []map[string]interface{}{}
+							if c.Bool("all") {
+								answerDataList = projectList.GetAll()
+
+							} else if c.NArg() > 0 {
+								for _, arg := range c.Args().Slice() {
+									index, err := utils.StringToInt(arg)
+									if err != nil {
+										return err
+									}
+									answerData, err := projectList.GetIndex(index - 1)
+									if err != nil {
+										return err
+									}
+									answerDataList = append(answerDataList, answerData)
+								}
+
+							} else {
+								answerData, err := projectList.Prompt("Enter drawbridge config number to open a tunnel for")
+								if err != nil {
+									return err
+								}
+								answerDataList = append(answerDataList, answerData)
+							}
+
+							tunnelAction := actions.TunnelAction{ConnectAction: actions.ConnectAction{Config: config}, Config: config, UserConfigFilePath: configFlagValue(os.Args[1:])}
+							return tunnelAction.Up(answerDataList)
+						},
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "all",
+								Usage: "Open tunnels for all drawbridge managed configs",
+							},
+						},
+					},
+					{
+						Name:  "down",
+						Usage: "Stop the background tunnel daemon",
+						Action: func(c *cli.Context) error {
+							fmt.Fprintln(c.App.Writer, c.Command.Usage)
+
+							tunnelAction := actions.TunnelAction{ConnectAction: actions.ConnectAction{Config: config}, Config: config}
+							return tunnelAction.Down()
+						},
+					},
+					{
+						Name:  "status",
+						Usage: "Show the status of background tunnels, and check that forwarded ports are healthy",
+						Action: func(c *cli.Context) error {
+							fmt.Fprintln(c.App.Writer, c.Command.Usage)
+
+							tunnelAction := actions.TunnelAction{ConnectAction: actions.ConnectAction{Config: config}, Config: config}
+							return tunnelAction.Status()
+						},
+					},
+					{
+						Name:      "run",
+						Usage:     "Run the tunnel daemon in the foreground (used internally by `tunnel up`)",
+						ArgsUsage: "config_filepath...",
+						Hidden:    true,
+						Action: func(c *cli.Context) error {
+							fmt.Fprintln(c.App.Writer, c.Command.Usage)
+
+							if c.NArg() == 0 {
+								return errors.InvalidArgumentsError("at least one config filepath is required")
+							}
+
+							tunnelAction := actions.TunnelAction{ConnectAction: actions.ConnectAction{Config: config}, Config: config}
+							return tunnelAction.Run(c.Args().Slice())
+						},
+					},
+				},
+			},
+			{
+				Name:  "init",
+				Usage: "Create a new drawbridge config file, by answering a few questions",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Overwrite the existing config file",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					initAction := actions.InitAction{Config: config}
+					return initAction.Start(userConfigFilePath, c.Bool("force"))
+				},
 			},
 			{
 				Name:  "update",
@@ -446,6 +855,28 @@ func createFlags(appConfig config.Interface) ([]cli.Flag, error) {
 			}
 
 			flags = append(flags, newFlag)
+		} else if questionType == "array" {
+			//repeatable, eg. `--services web --services api`
+			newFlag := &cli.StringSliceFlag{
+				Name:  k,
+				Usage: v.Description,
+			}
+			defaultValue, ok := v.DefaultValue.([]interface{})
+			if ok {
+				defaultItems := []string{}
+				for _, defaultItem := range defaultValue {
+					defaultItems = append(defaultItems, fmt.Sprintf("%v", defaultItem))
+				}
+				newFlag.Value = cli.NewStringSlice(defaultItems...)
+			}
+
+			flags = append(flags, newFlag)
+		} else if questionType == "object" {
+			//JSON/YAML flow syntax, eg. `--database '{host: db1, port: 5432}'`
+			flags = append(flags, &cli.StringFlag{
+				Name:  k,
+				Usage: v.Description,
+			})
 		}
 	}
 	return flags, nil
@@ -487,8 +918,174 @@ func createFlagHandler(appConfig config.Interface, defaultValues map[string]inte
 
 		} else if questionType == "boolean" {
 			cliAnswers[questionKey] = c.Bool(questionKey)
+
+		} else if questionType == "array" {
+			cliAnswers[questionKey], err = question.ConvertArrayAnswer(c.StringSlice(questionKey))
+			if err != nil {
+				return nil, err
+			}
+
+		} else if questionType == "object" {
+			cliAnswers[questionKey], err = question.ConvertAnswer(c.String(questionKey))
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	return cliAnswers, nil
 }
+
+type execArguments struct {
+	index    int
+	hosts    []string
+	command  string
+	parallel int
+}
+
+// Flag parsing stops at the first positional argument, so `exec 1 --hosts a,b -- cmd` leaves the flags in the argument
+// list. Handle flags in either position, and split the config number from the command at `--`.
+func parseExecArgs(args []string, hostsFlag string, parallelFlag int) (execArguments, error) {
+	execArgs := execArguments{parallel: parallelFlag}
+
+	positional := []string{}
+	commandArgs := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			commandArgs = args[i+1:]
+			break
+		} else if arg == "--hosts" && i+1 < len(args) {
+			hostsFlag = args[i+1]
+			i++
+		} else if strings.HasPrefix(arg, "--hosts=") {
+			hostsFlag = strings.TrimPrefix(arg, "--hosts=")
+		} else if arg == "--parallel" && i+1 < len(args) {
+			parallel, err := parseParallelArg(args[i+1])
+			if err != nil {
+				return execArgs, err
+			}
+			execArgs.parallel = parallel
+			i++
+		} else if strings.HasPrefix(arg, "--parallel=") {
+			parallel, err := parseParallelArg(strings.TrimPrefix(arg, "--parallel="))
+			if err != nil {
+				return execArgs, err
+			}
+			execArgs.parallel = parallel
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	// without `--`, the optional config number is followed by the command.
+	if len(commandArgs) == 0 && len(positional) > 0 {
+		if _, err := utils.StringToInt(positional[0]); err == nil && len(positional) > 1 {
+			commandArgs = positional[1:]
+			positional = positional[:1]
+		} else {
+			commandArgs = positional
+			positional = []string{}
+		}
+	}
+
+	if len(positional) > 1 {
+		return execArgs, errors.InvalidArgumentsError(fmt.Sprintf("Unexpected arguments before `--`: %v", positional))
+	} else if len(positional) == 1 {
+		index, err := utils.StringToInt(positional[0])
+		if err != nil {
+			return execArgs, errors.InvalidArgumentsError("Invalid `config_id`, please specify a number")
+		}
+		execArgs.index = index
+	}
+
+	for _, host := range strings.Split(hostsFlag, ",") {
+		if host = strings.TrimSpace(host); len(host) > 0 {
+			execArgs.hosts = append(execArgs.hosts, host)
+		}
+	}
+	if len(execArgs.hosts) == 0 {
+		return execArgs, errors.InvalidArgumentsError("at least one host must be specified with `--hosts`")
+	}
+
+	if len(commandArgs) == 0 {
+		return execArgs, errors.InvalidArgumentsError("a command must be specified after `--`")
+	}
+	execArgs.command = strings.Join(commandArgs, " ")
+	return execArgs, nil
+}
+
+func parseParallelArg(value string) (int, error) {
+	parallel, err := utils.StringToInt(value)
+	if err != nil {
+		return 0, errors.InvalidArgumentsError("Invalid `--parallel`, please specify a number")
+	}
+	return parallel, nil
+}
+
+// selectAnswerData returns the answer data for a config number (1 based), or prompts the user if it's empty.
+func selectAnswerData(configData config.Interface, strIndex string, promptMessage string) (map[string]interface{}, error) {
+	projectList, err := project.CreateProjectListFromConfigDir(configData)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(strIndex) == 0 {
+		return projectList.Prompt(promptMessage)
+	}
+
+	index, err := utils.StringToInt(strIndex)
+	if err != nil {
+		return nil, errors.InvalidArgumentsError("Invalid `config_id`, please specify a number")
+	}
+	return projectList.GetIndex(index - 1)
+}
+
+// configFlagValue returns the value of the global `--config` flag (which must appear before the command).
+func configFlagValue(args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			//global flags must appear before the command.
+			return ""
+		} else if arg == "--config" && i+1 < len(args) {
+			return args[i+1]
+		} else if strings.HasPrefix(arg, "--config=") {
+			return strings.TrimPrefix(arg, "--config=")
+		}
+	}
+	return ""
+}
+
+// loadConfig loads the config file layers. `config validate` must be able to report the issues in invalid config files
+// (and `init` may replace them), so they fall back to the default config.
+func loadConfig(configFileLayers []string, args []string) (config.Interface, error) {
+	appConfig, err := config.Load(configFileLayers)
+	if err != nil && (isCommand(args, "config", "validate") || isCommand(args, "init")) {
+		return config.Create()
+	}
+	return appConfig, err
+}
+
+// isCommand returns true if the args start with the (sub)command. Global flags are skipped.
+func isCommand(args []string, command ...string) bool {
+	commandArgs := []string{}
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--config" {
+			//skip the flag value
+			i++
+		} else if !strings.HasPrefix(args[i], "-") {
+			commandArgs = append(commandArgs, args[i])
+		}
+	}
+
+	if len(commandArgs) < len(command) {
+		return false
+	}
+	for i := range command {
+		if commandArgs[i] != command[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseExecArgs_ParallelFlag(t *testing.T) {
+	t.Parallel()
+
+	//test
+	execArgs, err := parseExecArgs([]string{"1", "--hosts", "web1,web2", "--parallel", "4", "--", "uptime"}, "", 1)
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, 1, execArgs.index)
+	require.Equal(t, []string{"web1", "web2"}, execArgs.hosts)
+	require.Equal(t, 4, execArgs.parallel, "should parse `--parallel N` after the config number")
+	require.Equal(t, "uptime", execArgs.command)
+}
+
+func TestParseExecArgs_ParallelFlagWithEquals(t *testing.T) {
+	t.Parallel()
+
+	//test
+	execArgs, err := parseExecArgs([]string{"1", "--hosts=web1,web2", "--parallel=4", "--", "uptime"}, "", 1)
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, 1, execArgs.index)
+	require.Equal(t, []string{"web1", "web2"}, execArgs.hosts)
+	require.Equal(t, 4, execArgs.parallel, "should parse `--parallel=N` after the config number")
+	require.Equal(t, "uptime", execArgs.command)
+}
+
+func TestParseExecArgs_ParallelFlagDefault(t *testing.T) {
+	t.Parallel()
+
+	//test
+	execArgs, err := parseExecArgs([]string{"--", "uptime"}, "web1", 8)
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, 8, execArgs.parallel, "should use the parsed `--parallel` flag when it's not repeated in the args")
+}
+
+func TestParseExecArgs_InvalidParallelFlag(t *testing.T) {
+	t.Parallel()
+
+	//test
+	_, err := parseExecArgs([]string{"1", "--hosts", "web1", "--parallel=many", "--", "uptime"}, "", 1)
+
+	//assert
+	require.Error(t, err, "should raise an error when `--parallel` is not a number")
+}
@@ -0,0 +1,28 @@
+package actions
+
+import (
+	"drawbridge/pkg/config"
+	"fmt"
+	"github.com/fatih/color"
+)
+
+type ConfigMigrateAction struct {
+	Config config.Interface
+}
+
+// Start rewrites every config file that uses an older schema version. A backup of each original file is kept.
+func (e *ConfigMigrateAction) Start(configFilePaths []string) error {
+	for _, configFilePath := range configFilePaths {
+		originalVersion, backupFilePath, err := config.MigrateConfigFile(configFilePath)
+		if err != nil {
+			return err
+		}
+
+		if len(backupFilePath) == 0 {
+			fmt.Printf("%v is up to date (version %v)\n", configFilePath, originalVersion)
+			continue
+		}
+		color.Green("Migrated %v from version %v to version %v. The original file was saved as %v", configFilePath, originalVersion, config.CurrentConfigVersion, backupFilePath)
+	}
+	return nil
+}
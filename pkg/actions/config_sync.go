@@ -0,0 +1,136 @@
+package actions
+
+import (
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"fmt"
+	"github.com/fatih/color"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type ConfigSyncAction struct {
+	Config config.Interface
+}
+
+// Start fetches the remote (shared) config, validates it and caches it in the config_dir. The cached file is merged
+// beneath the local config files every time drawbridge starts.
+func (e *ConfigSyncAction) Start() error {
+	remoteConfig, err := e.Config.GetRemoteConfig()
+	if err != nil {
+		return err
+	}
+	if len(remoteConfig.Url) == 0 {
+		return errors.ConfigValidationError("`options.remote_config.url` must be set in your config file before syncing")
+	}
+
+	fmt.Printf("Fetching remote config (%v): %v\n", remoteConfig.SourceType(), remoteConfig.Url)
+	var remoteContent []byte
+	switch remoteConfig.SourceType() {
+	case config.RemoteConfigTypeHttp:
+		remoteContent, err = fetchHttpConfig(remoteConfig)
+	case config.RemoteConfigTypeGit:
+		remoteContent, err = fetchGitConfig(remoteConfig)
+	default:
+		err = errors.ConfigValidationError(fmt.Sprintf("Unsupported remote_config type: %v", remoteConfig.Type))
+	}
+	if err != nil {
+		return err
+	}
+
+	cacheFilePath, err := config.RemoteConfigCacheFilePath(e.Config.GetString("options.config_dir"))
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(cacheFilePath), 0700)
+	if err != nil {
+		return err
+	}
+
+	// validate the remote config before replacing the cached copy.
+	stagingFile, err := ioutil.TempFile(filepath.Dir(cacheFilePath), ".remote_config")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stagingFile.Name())
+	_, err = stagingFile.Write(remoteContent)
+	stagingFile.Close()
+	if err != nil {
+		return err
+	}
+
+	err = e.Config.ValidateConfigFile(stagingFile.Name())
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(stagingFile.Name(), cacheFilePath)
+	if err != nil {
+		return err
+	}
+
+	color.Green("Remote config cached at %v. It will be merged beneath your local config files.", cacheFilePath)
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+func fetchHttpConfig(remoteConfig config.RemoteConfig) ([]byte, error) {
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(remoteConfig.Url)
+	if err != nil {
+		return nil, errors.RemoteConfigError(fmt.Sprintf("Could not download remote config from %v: %v", remoteConfig.Url, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.RemoteConfigError(fmt.Sprintf("Could not download remote config from %v: %v", remoteConfig.Url, resp.Status))
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// fetchGitConfig fetches a single ref (branch, tag or commit) from the repository, and reads the config file from it.
+func fetchGitConfig(remoteConfig config.RemoteConfig) ([]byte, error) {
+	gitBin, err := exec.LookPath("git")
+	if err != nil {
+		return nil, errors.DependencyMissingError("git is missing")
+	}
+
+	repoDir, err := ioutil.TempDir("", "drawbridge-remote-config")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(repoDir)
+
+	ref := remoteConfig.Ref
+	if len(ref) == 0 {
+		ref = "HEAD"
+	}
+
+	gitCommands := [][]string{
+		{"init", "--quiet"},
+		{"fetch", "--quiet", "--depth", "1", remoteConfig.Url, ref},
+	}
+	for _, gitArgs := range gitCommands {
+		gitCmd := exec.Command(gitBin, gitArgs...)
+		gitCmd.Dir = repoDir
+		output, err := gitCmd.CombinedOutput()
+		if err != nil {
+			return nil, errors.RemoteConfigError(fmt.Sprintf("`git %v` failed: %v", strings.Join(gitArgs, " "), strings.TrimSpace(string(output))))
+		}
+	}
+
+	showCmd := exec.Command(gitBin, "show", fmt.Sprintf("FETCH_HEAD:%v", remoteConfig.FilePath()))
+	showCmd.Dir = repoDir
+	content, err := showCmd.Output()
+	if err != nil {
+		return nil, errors.RemoteConfigError(fmt.Sprintf("Could not find %v at %v in %v", remoteConfig.FilePath(), ref, remoteConfig.Url))
+	}
+	return content, nil
+}
@@ -0,0 +1,108 @@
+package actions_test
+
+import (
+	"drawbridge/pkg/actions"
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+)
+
+func TestConfigSyncAction_Start_Http(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "version: 1\noptions:\n  ui_question_hidden: ['username']\n")
+	}))
+	defer server.Close()
+
+	configData, err := config.Create()
+	require.NoError(t, err)
+	parentPath, err := ioutil.TempDir("", "")
+	defer os.RemoveAll(parentPath)
+	configData.Set("options.config_dir", parentPath)
+	configData.Set("options.remote_config", map[string]interface{}{"url": server.URL + "/drawbridge.yaml"})
+
+	configSyncAction := actions.ConfigSyncAction{Config: configData}
+
+	//test
+	err = configSyncAction.Start()
+
+	//assert
+	require.NoError(t, err, "should not raise an error when syncing remote config")
+	require.FileExists(t, path.Join(parentPath, config.RemoteConfigCacheFileName), "should cache remote config in config_dir")
+}
+
+func TestConfigSyncAction_Start_InvalidRemoteConfig(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "unsupported_key: true\n")
+	}))
+	defer server.Close()
+
+	configData, err := config.Create()
+	require.NoError(t, err)
+	parentPath, err := ioutil.TempDir("", "")
+	defer os.RemoveAll(parentPath)
+	configData.Set("options.config_dir", parentPath)
+	configData.Set("options.remote_config", map[string]interface{}{"url": server.URL + "/drawbridge.yaml"})
+
+	configSyncAction := actions.ConfigSyncAction{Config: configData}
+
+	//test
+	err = configSyncAction.Start()
+
+	//assert
+	require.Error(t, err, "should raise an error when remote config is invalid")
+	require.False(t, utils.FileExists(path.Join(parentPath, config.RemoteConfigCacheFileName)), "should not cache invalid remote config")
+}
+
+func TestConfigSyncAction_Start_Git(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is missing")
+	}
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	defer os.RemoveAll(parentPath)
+	repoPath := path.Join(parentPath, "repo")
+	require.NoError(t, os.MkdirAll(path.Join(repoPath, "team"), 0777))
+	require.NoError(t, utils.FileWrite(path.Join(repoPath, "team", "drawbridge.yaml"), "version: 1\n", 0644, false))
+	for _, gitArgs := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "shared config"},
+		{"tag", "v1"},
+	} {
+		gitCmd := exec.Command("git", gitArgs...)
+		gitCmd.Dir = repoPath
+		output, err := gitCmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+
+	configData, err := config.Create()
+	require.NoError(t, err)
+	configDir := path.Join(parentPath, "drawbridge")
+	configData.Set("options.config_dir", configDir)
+	configData.Set("options.remote_config", map[string]interface{}{"url": repoPath, "ref": "v1", "path": "team/drawbridge.yaml"})
+
+	configSyncAction := actions.ConfigSyncAction{Config: configData}
+
+	//test
+	err = configSyncAction.Start()
+
+	//assert
+	require.NoError(t, err, "should not raise an error when syncing remote config from git")
+	require.FileExists(t, path.Join(configDir, config.RemoteConfigCacheFileName), "should cache remote config in config_dir")
+}
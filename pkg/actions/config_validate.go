@@ -0,0 +1,34 @@
+package actions
+
+import (
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"fmt"
+	"github.com/fatih/color"
+)
+
+type ConfigValidateAction struct {
+	Config config.Interface
+}
+
+// Start validates every config file layer, and the merged config. All issues are printed with their file & key location.
+func (e *ConfigValidateAction) Start(configFileLayers []string) error {
+	for _, configFilePath := range configFileLayers {
+		fmt.Printf("Validating config file: %v\n", configFilePath)
+	}
+
+	issues, err := config.ValidateLayers(configFileLayers)
+	if err != nil {
+		return err
+	}
+
+	if len(issues) > 0 {
+		for _, issue := range issues {
+			color.HiRed("- %v", issue)
+		}
+		return errors.ConfigValidationError(fmt.Sprintf("Found %v issue(s) in your config", len(issues)))
+	}
+
+	color.Green("Config is valid (%v file(s) checked)", len(configFileLayers))
+	return nil
+}
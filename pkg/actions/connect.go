@@ -1,27 +1,44 @@
 package actions
 
 import (
+	"bytes"
 	"crypto/x509"
 	"drawbridge/pkg/config"
 	"drawbridge/pkg/errors"
+	"drawbridge/pkg/sshclient"
 	"drawbridge/pkg/utils"
 	"encoding/pem"
 	"fmt"
+	"github.com/fatih/color"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 	"io/ioutil"
 	"net"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"syscall"
 )
 
 type ConnectAction struct {
 	Config config.Interface
+
+	// Native connects using the built-in ssh client instead of exec'ing the ssh binary.
+	Native bool
+
+	// SkipPreflight disables the bastion reachability check.
+	SkipPreflight bool
+
+	// ReloadKey forces the pem key to be re-added to the ssh-agent, even if it's already loaded.
+	ReloadKey bool
+
+	// privateAgent is started when there is no ssh-agent running (SSH_AUTH_SOCK is unset).
+	privateAgent *sshclient.PrivateAgent
 }
 
 func (e *ConnectAction) Start(answerData map[string]interface{}, destHostname string) error {
+	defer e.closeAgent()
 
 	//"-c", "command1; command2; command3; ..."
 
@@ -42,13 +59,25 @@ func (e *ConnectAction) Start(answerData map[string]interface{}, destHostname st
 
 	//TODO: Print the lines we're running.
 
-	//TODO: Check that the bastion host is accessible.
+	err = e.Preflight(tmplConfigFilepath)
+	if err != nil {
+		return err
+	}
 
 	err = e.SshAgentAddPemKey(tmplPemFilepath)
 	if err != nil {
 		return err
 	}
 
+	if e.Native {
+		err = e.nativeConnect(tmplConfigFilepath, destHostname)
+		if _, ok := err.(errors.SshConfigUnsupportedError); ok {
+			color.Yellow("WARNING: %v. Falling back to ssh binary", err)
+		} else {
+			return err
+		}
+	}
+
 	//https://gobyexample.com/execing-processes
 	//https://groob.io/posts/golang-execve/
 
@@ -64,7 +93,7 @@ func (e *ConnectAction) Start(answerData map[string]interface{}, destHostname st
 	}
 	args := []string{"ssh", configHost, "-F", tmplConfigFilepath}
 
-	return syscall.Exec(sshBin, args, os.Environ())
+	return e.execWithAgent(sshBin, args)
 }
 
 func (e *ConnectAction) SshAgentAddPemKey(pemFilepath string) error {
@@ -73,19 +102,35 @@ func (e *ConnectAction) SshAgentAddPemKey(pemFilepath string) error {
 		return errors.PemKeyMissingError(fmt.Sprintf("No pem file exists at %v", pemFilepath))
 	}
 
-	//ensure that the ssh-agent is available on this machine.
-	_, err := exec.LookPath("ssh-agent")
+	//read the pem file data
+	keyData, err := ioutil.ReadFile(pemFilepath)
 	if err != nil {
-		return errors.DependencyMissingError("ssh-agent is missing")
+		return err
 	}
 
-	//read the pem file data
-	keyData, err := ioutil.ReadFile(pemFilepath)
+	agentClient, err := e.sshAgentClient()
 	if err != nil {
 		return err
 	}
 
-	//TODO: check if this pemfile is already added to the ssh-agent
+	//check if this pemfile is already added to the ssh-agent (before prompting for a passphrase)
+	publicKey := pemPublicKey(pemFilepath, keyData)
+	if publicKey != nil {
+		loaded, err := agentHasKey(agentClient, publicKey)
+		if err != nil {
+			return err
+		}
+
+		if loaded && !e.ReloadKey {
+			fmt.Printf("PEM key (%v) is already loaded in ssh-agent\n", pemFilepath)
+			return nil
+		} else if loaded {
+			err = agentClient.Remove(publicKey)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
 	//decode the ssh pem key (and handle encypted/passphrase protected keys)
 	//https://stackoverflow.com/questions/42105432/how-to-use-an-encrypted-private-key-with-golang-ssh
@@ -96,7 +141,7 @@ func (e *ConnectAction) SshAgentAddPemKey(pemFilepath string) error {
 	fmt.Printf("Adding PEM key (%v) to ssh-agent\n", pemFilepath)
 
 	var privateKeyData interface{}
-	if x509.IsEncryptedPEMBlock(block) {
+	if block != nil && x509.IsEncryptedPEMBlock(block) || isPassphraseMissing(keyData) {
 		//inform the user that the key is encrypted.
 
 		passphrase, err := utils.StdinQueryPassword(fmt.Sprintf("The key at %v is encrypted and requires a passphrase. Please enter it below:", pemFilepath))
@@ -105,24 +150,207 @@ func (e *ConnectAction) SshAgentAddPemKey(pemFilepath string) error {
 		}
 
 		privateKeyData, err = ssh.ParseRawPrivateKeyWithPassphrase(keyData, []byte(passphrase))
+		if err != nil {
+			return err
+		}
 	} else {
 		privateKeyData, err = ssh.ParseRawPrivateKey(keyData)
+		if err != nil {
+			return err
+		}
 	}
 
 	// register the privatekey with ssh-agent
 
-	socket := os.Getenv("SSH_AUTH_SOCK")
-	conn, err := net.Dial("unix", socket)
+	lifetimeSecs, confirmBeforeUse, err := e.agentKeyOptions()
 	if err != nil {
 		return err
 	}
-	agentClient := agent.NewClient(conn)
 
 	err = agentClient.Add(agent.AddedKey{
-		PrivateKey:   privateKeyData,
-		Comment:      fmt.Sprintf("(drawbridge) - %v", pemFilepath),
-		LifetimeSecs: 3600, //for safety we should limit this key's use for 1h
+		PrivateKey:       privateKeyData,
+		Comment:          fmt.Sprintf("(drawbridge) - %v", pemFilepath),
+		LifetimeSecs:     lifetimeSecs,
+		ConfirmBeforeUse: confirmBeforeUse,
 	})
 
 	return err
 }
+
+// agentKeyOptions returns the ssh-agent key lifetime (0 means no limit) & confirm-before-use settings. The active config
+// template can override the global options.
+func (e *ConnectAction) agentKeyOptions() (uint32, bool, error) {
+	if e.Config == nil {
+		//for safety we should limit this key's use for 1h
+		return 3600, false, nil
+	}
+
+	lifetime := e.Config.GetInt("options.agent_key_lifetime")
+	confirm := e.Config.GetBool("options.agent_key_confirm")
+
+	tmplData, err := e.Config.GetActiveConfigTemplate()
+	if err != nil {
+		return 0, false, err
+	}
+	if tmplData.AgentKeyLifetime != nil {
+		lifetime = *tmplData.AgentKeyLifetime
+	}
+	if tmplData.AgentKeyConfirm != nil {
+		confirm = *tmplData.AgentKeyConfirm
+	}
+
+	if lifetime < 0 {
+		return 0, false, errors.ConfigValidationError(fmt.Sprintf("agent_key_lifetime must be a positive number of seconds: %v", lifetime))
+	}
+	return uint32(lifetime), confirm, nil
+}
+
+// Preflight checks that the bastion host in the rendered ssh config is reachable, before handing off to ssh.
+func (e *ConnectAction) Preflight(configFilepath string) error {
+	if e.SkipPreflight {
+		return nil
+	}
+
+	sshConfig, err := sshclient.ParseConfigFile(configFilepath)
+	if err != nil {
+		return err
+	}
+	return sshclient.Preflight(sshConfig, sshclient.PreflightTimeout)
+}
+
+func (e *ConnectAction) nativeConnect(configFilepath string, destHostname string) error {
+	sshConfig, err := sshclient.ParseConfigFile(configFilepath)
+	if err != nil {
+		return err
+	}
+
+	agentClient, err := e.sshAgentClient()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Opening ssh tunnel (native)")
+	client, err := sshclient.Dial(sshConfig, destHostname, agentClient)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	listeners, err := client.StartLocalForwards()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}()
+
+	return client.Shell()
+}
+
+// pemPublicKey returns the public key for a pem file, without requiring a passphrase. The public key is read from the
+// unencrypted key, the (unencrypted) header of an OpenSSH format key, or a `.pub` file alongside the pem file.
+// Returns nil if the public key cannot be determined.
+func pemPublicKey(pemFilepath string, keyData []byte) ssh.PublicKey {
+	privateKeyData, err := ssh.ParseRawPrivateKey(keyData)
+	if err == nil {
+		signer, err := ssh.NewSignerFromKey(privateKeyData)
+		if err == nil {
+			return signer.PublicKey()
+		}
+	} else if passphraseErr, ok := err.(*ssh.PassphraseMissingError); ok && passphraseErr.PublicKey != nil {
+		return passphraseErr.PublicKey
+	}
+
+	publicKeyData, err := ioutil.ReadFile(pemFilepath + ".pub")
+	if err != nil {
+		return nil
+	}
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(publicKeyData)
+	if err != nil {
+		return nil
+	}
+	return publicKey
+}
+
+func isPassphraseMissing(keyData []byte) bool {
+	_, err := ssh.ParseRawPrivateKey(keyData)
+	_, ok := err.(*ssh.PassphraseMissingError)
+	return ok
+}
+
+func agentHasKey(agentClient agent.Agent, publicKey ssh.PublicKey) (bool, error) {
+	agentKeys, err := agentClient.List()
+	if err != nil {
+		return false, err
+	}
+	for _, agentKey := range agentKeys {
+		if bytes.Equal(agentKey.Marshal(), publicKey.Marshal()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sshAgentClient connects to the running ssh-agent. If there is no ssh-agent (SSH_AUTH_SOCK is unset), a private
+// in-process agent is started instead, which is shared with any ssh/scp process started by execWithAgent.
+func (e *ConnectAction) sshAgentClient() (agent.ExtendedAgent, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if e.privateAgent != nil {
+		socket = e.privateAgent.SocketPath
+	} else if len(socket) == 0 {
+		privateAgent, err := sshclient.StartPrivateAgent()
+		if err != nil {
+			return nil, err
+		}
+		color.Yellow("WARNING: SSH_AUTH_SOCK is not set, starting a private ssh-agent. Keys will be removed when drawbridge exits")
+		e.privateAgent = privateAgent
+		socket = privateAgent.SocketPath
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	return agent.NewClient(conn), nil
+}
+
+// agentEnviron returns the environment for ssh/scp child processes, so that they can use the private agent (if any).
+func (e *ConnectAction) agentEnviron() []string {
+	if e.privateAgent != nil {
+		return e.privateAgent.Environ()
+	}
+	return os.Environ()
+}
+
+// closeAgent tears down the private agent (if any).
+func (e *ConnectAction) closeAgent() error {
+	if e.privateAgent == nil {
+		return nil
+	}
+	err := e.privateAgent.Close()
+	e.privateAgent = nil
+	return err
+}
+
+// execWithAgent replaces the current process with the ssh/scp binary. When a private agent is running, the binary
+// is started as a child process instead (so that the agent stays available), and the agent is torn down afterwards.
+func (e *ConnectAction) execWithAgent(binPath string, args []string) error {
+	if e.privateAgent == nil {
+		return syscall.Exec(binPath, args, os.Environ())
+	}
+
+	cmd := exec.Command(binPath, args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = e.agentEnviron()
+
+	// the child process handles Ctrl+C, drawbridge must stay alive to clean up the agent.
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	return cmd.Run()
+}
@@ -4,6 +4,9 @@ import (
 	"testing"
 	"github.com/stretchr/testify/require"
 	"drawbridge/pkg/actions"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"io/ioutil"
 
 	"path"
 )
@@ -13,6 +16,7 @@ func TestConnectAction_SshAgentAddPemKey(t *testing.T) {
 
 	//setup
 	connectAction := actions.ConnectAction{}
+	defer connectAction.CloseAgent()
 
 	//test
 	err := connectAction.SshAgentAddPemKey(path.Join("testdata", "connect/test_rsa.pem"))
@@ -35,3 +39,76 @@ func TestConnectAction_SshAgentAddPemKey_InvalidPath(t *testing.T) {
 	//assert
 	require.Error(t, err, "should raise an error when adding invalid pem key to ssh-agent")
 }
+
+func TestConnectAction_SshAgentAddPemKey_AlreadyLoaded(t *testing.T) {
+	// not parallel, the ssh-agent keys are shared with the other tests.
+
+	//setup
+	pemFilepath := path.Join("testdata", "connect/test_rsa.pem")
+	connectAction := actions.ConnectAction{}
+	defer connectAction.CloseAgent()
+	agentClient, err := connectAction.SshAgentClient()
+	require.NoError(t, err)
+	publicKey := preloadAgentKey(t, agentClient, pemFilepath)
+
+	//test
+	err = connectAction.SshAgentAddPemKey(pemFilepath)
+
+	//assert
+	require.NoError(t, err, "should skip pem key that is already loaded in ssh-agent")
+	agentKeys := listAgentKeys(t, agentClient, publicKey)
+	require.Len(t, agentKeys, 1, "should not add the pem key twice")
+	require.Equal(t, "preloaded", agentKeys[0].Comment, "should keep the loaded pem key")
+}
+
+func TestConnectAction_SshAgentAddPemKey_ReloadKey(t *testing.T) {
+	// not parallel, the ssh-agent keys are shared with the other tests.
+
+	//setup
+	pemFilepath := path.Join("testdata", "connect/test_rsa.pem")
+	connectAction := actions.ConnectAction{ReloadKey: true}
+	defer connectAction.CloseAgent()
+	agentClient, err := connectAction.SshAgentClient()
+	require.NoError(t, err)
+	publicKey := preloadAgentKey(t, agentClient, pemFilepath)
+
+	//test
+	err = connectAction.SshAgentAddPemKey(pemFilepath)
+
+	//assert
+	require.NoError(t, err, "should re-add pem key that is already loaded in ssh-agent")
+	agentKeys := listAgentKeys(t, agentClient, publicKey)
+	require.Len(t, agentKeys, 1, "should replace the loaded pem key")
+	require.Equal(t, "(drawbridge) - "+pemFilepath, agentKeys[0].Comment, "should re-add the pem key")
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+// preloadAgentKey adds the pem key to the ssh-agent (with a placeholder comment), and returns its public key.
+func preloadAgentKey(t *testing.T, agentClient agent.ExtendedAgent, pemFilepath string) ssh.PublicKey {
+	keyData, err := ioutil.ReadFile(pemFilepath)
+	require.NoError(t, err)
+	privateKey, err := ssh.ParseRawPrivateKey(keyData)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	require.NoError(t, err)
+
+	err = agentClient.Add(agent.AddedKey{PrivateKey: privateKey, Comment: "preloaded"})
+	require.NoError(t, err)
+	return signer.PublicKey()
+}
+
+// listAgentKeys returns the ssh-agent entries for the public key.
+func listAgentKeys(t *testing.T, agentClient agent.ExtendedAgent, publicKey ssh.PublicKey) []*agent.Key {
+	agentKeys, err := agentClient.List()
+	require.NoError(t, err)
+
+	matchingKeys := []*agent.Key{}
+	for _, agentKey := range agentKeys {
+		if string(agentKey.Marshal()) == string(publicKey.Marshal()) {
+			matchingKeys = append(matchingKeys, agentKey)
+		}
+	}
+	return matchingKeys
+}
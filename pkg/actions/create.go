@@ -2,14 +2,13 @@ package actions
 
 import (
 	"drawbridge/pkg/config"
-	"drawbridge/pkg/errors"
+	"drawbridge/pkg/secret"
 	"drawbridge/pkg/utils"
 	"fmt"
 	"github.com/fatih/color"
 	"gopkg.in/yaml.v2"
 	"path"
-	"sort"
-	"strconv"
+	"strings"
 )
 
 type CreateAction struct {
@@ -27,6 +26,7 @@ func (e *CreateAction) Start(cliAnswerData map[string]interface{}, dryRun bool)
 	if err != nil {
 		return err
 	}
+	secretKeys := config.SecretQuestionKeys(questions)
 	for questionKey, question := range questions {
 		if question.DefaultValue != nil {
 			answerData[questionKey] = question.DefaultValue
@@ -42,7 +42,8 @@ func (e *CreateAction) Start(cliAnswerData map[string]interface{}, dryRun bool)
 
 	fmt.Println("\nCurrent Answers:")
 
-	questionKeys := utils.MapKeys(answerData)
+	redactedAnswerData := secret.Redact(answerData, secretKeys)
+	questionKeys := utils.MapKeys(redactedAnswerData)
 	for _, questionKey := range questionKeys {
 		if utils.SliceIncludes(e.Config.InternalQuestionKeys(), questionKey) {
 			continue
@@ -50,7 +51,7 @@ func (e *CreateAction) Start(cliAnswerData map[string]interface{}, dryRun bool)
 
 		fmt.Printf("%v: %v\n",
 			questionKey,
-			color.GreenString(fmt.Sprintf("%v", answerData[questionKey])))
+			color.GreenString(fmt.Sprintf("%v", redactedAnswerData[questionKey])))
 	}
 
 	// ensure that that all questions are answered, query user if missing anything.
@@ -70,13 +71,30 @@ func (e *CreateAction) Start(cliAnswerData map[string]interface{}, dryRun bool)
 		}
 	}
 
+	// resolve the config variables, so they're available to the config & custom templates (as `.vars`)
+	answerData["vars"], err = e.Config.GetVariables(answerData)
+	if err != nil {
+		return err
+	}
+
 	// write the config template, make sure we "fix" the config filepath
 	activeConfigTemplate, err := e.Config.GetActiveConfigTemplate()
 	if err != nil {
 		return err
 	}
 
-	configTemplateData, err := activeConfigTemplate.WriteTemplate(answerData, e.Config.InternalQuestionKeys(), dryRun)
+	// secret answers are stored in the secret backend, identified by the rendered config filepath. They're stored before
+	// anything is written, so a backend failure doesn't leave a config file without an answers file.
+	configFilePath, err := activeConfigTemplate.ConfigFilePath(answerData)
+	if err != nil {
+		return err
+	}
+	err = e.StoreSecrets(configFilePath, answerData, secretKeys, dryRun)
+	if err != nil {
+		return err
+	}
+
+	configTemplateData, err := activeConfigTemplate.WriteTemplate(answerData, e.Config.InternalQuestionKeys(), secretKeys, dryRun)
 	if err != nil {
 		return err
 	}
@@ -100,8 +118,38 @@ func (e *CreateAction) Start(cliAnswerData map[string]interface{}, dryRun bool)
 		answerData["custom"] = append(answerData["custom"].([]interface{}), customTemplateData)
 	}
 
-	// write the answers.yaml file
-	return e.WriteAnswersFile(path.Base(activeConfigTemplate.FilePath), answerData, dryRun)
+	// write the answers.yaml file, without the secret answers
+	return e.WriteAnswersFile(path.Base(activeConfigTemplate.FilePath), secret.Redact(answerData, secretKeys), dryRun)
+}
+
+// StoreSecrets saves the (non-empty) secret answers in the configured secret backend.
+func (e *CreateAction) StoreSecrets(configFilePath string, answerData map[string]interface{}, secretKeys []string, dryRun bool) error {
+	if len(secretKeys) == 0 {
+		return nil
+	}
+
+	secretBackend, err := e.Config.GetSecretBackend()
+	if err != nil {
+		return err
+	}
+
+	for _, secretKey := range secretKeys {
+		value, ok := answerData[secretKey].(string)
+		if !ok {
+			continue
+		}
+
+		secretId := secret.Id(configFilePath, secretKey)
+		if dryRun {
+			fmt.Printf("%v Would have stored secret %v (%v backend)\n", color.GreenString("[DRYRUN]"), secretId, secretBackend.Name())
+			continue
+		}
+		err = secretBackend.Set(secretId, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 func (e *CreateAction) WriteAnswersFile(baseName string, answerData map[string]interface{}, dryRun bool) error {
 	answersFilePath, err := utils.PopulatePathTemplate(path.Join(e.Config.GetString("options.config_dir"), fmt.Sprintf(".%v.answers.yaml", baseName)), answerData)
@@ -120,17 +168,43 @@ func (e *CreateAction) WriteAnswersFile(baseName string, answerData map[string]i
 	return nil
 }
 
+// Query asks the user for every required answer that is missing. Questions are asked in dependency order, so that the
+// `when` and `enum_when` conditions can be evaluated using the answers they depend on.
 func (e *CreateAction) Query(questions map[string]config.Question, answerData map[string]interface{}) (map[string]interface{}, error) {
 
-	questionKeys := []string{}
-	for k := range questions {
-		questionKeys = append(questionKeys, k)
+	questionKeys, err := config.OrderQuestions(questions)
+	if err != nil {
+		return nil, err
 	}
-	sort.Strings(questionKeys)
 
 	for _, questionKey := range questionKeys {
 		questionData := questions[questionKey]
 
+		applicable, err := questionData.Applicable(answerData)
+		if err != nil {
+			return nil, err
+		}
+		if !applicable {
+			//the question does not apply to these answers, ignore any default/provided answer.
+			answerData[questionKey] = nil
+			continue
+		}
+
+		questionData, err = questionData.ForAnswers(answerData)
+		if err != nil {
+			return nil, err
+		}
+
+		if answer, ok := answerData[questionKey]; ok && answer != nil {
+			if err := questionData.Validate(questionKey, answer); err != nil {
+				if questionData.Secret {
+					answer = secret.Redacted
+				}
+				color.Yellow("WARNING: `%v` is not a valid answer for `%v` (%v)", answer, questionKey, err)
+				delete(answerData, questionKey)
+			}
+		}
+
 		val, ok := questionData.Schema["required"]
 		required := ok && val.(bool)
 
@@ -145,11 +219,30 @@ func (e *CreateAction) Query(questions map[string]config.Question, answerData ma
 
 func (e *CreateAction) queryResponse(questionKey string, question config.Question) interface{} {
 
+	//questions with a fixed list of answers (`enum` or `choices_from`) are answered using a numbered pick list.
+	if choices := config.EnumChoices(question.Schema["enum"]); len(choices) > 0 {
+		return e.queryChoice(questionKey, question, choices)
+	} else if itemChoices := question.ItemChoices(); question.GetType() == "array" && len(itemChoices) > 0 {
+		return e.queryMultiChoice(questionKey, question, itemChoices)
+	}
+
 	for true {
 		//this question is not answered, and it is required. We should ask the user.
-		answer := utils.StdinQuery(fmt.Sprintf("Please enter a value for `%s` [%s] - %s:", questionKey, question.GetType(), question.Description))
+		prompt := fmt.Sprintf("Please enter a value for `%s` [%s] - %s:", questionKey, question.GetType(), question.Description)
+		var answer string
+		if question.Secret {
+			//secret answers are not echoed.
+			var err error
+			answer, err = utils.StdinQueryPassword(prompt)
+			if err != nil {
+				fmt.Printf("%v\n", err)
+				continue
+			}
+		} else {
+			answer = utils.StdinQuery(prompt)
+		}
 
-		answerTyped, err := convertAnswerType(answer, question.GetType())
+		answerTyped, err := question.ConvertAnswer(answer)
 		if err != nil {
 			fmt.Printf("%v\n", err)
 			continue
@@ -168,29 +261,62 @@ func (e *CreateAction) queryResponse(questionKey string, question config.Questio
 	return nil
 }
 
-func convertAnswerType(answer string, questionType string) (interface{}, error) {
-	if questionType == "integer" {
-		answer, err := strconv.ParseInt(answer, 10, 64)
-		if err != nil {
-			return nil, err
+func (e *CreateAction) queryChoice(questionKey string, question config.Question, choices []interface{}) interface{} {
+	for {
+		fmt.Println(color.BlueString("Please select a value for `%s` - %s:", questionKey, question.Description))
+		for i, choice := range choices {
+			fmt.Printf("  %v) %v\n", i+1, choice)
 		}
-		return answer, nil
-	} else if questionType == "number" {
-		answer, err := strconv.ParseFloat(answer, 64)
-		if err != nil {
-			return nil, err
+
+		answer := utils.StdinQuery(fmt.Sprintf("Enter a number [1-%v]:", len(choices)))
+		index, err := utils.StringToInt(answer)
+		if err != nil || index < 1 || index > len(choices) {
+			color.HiRed("Invalid selection `%v`\n", answer)
+			continue
 		}
-		return answer, nil
-	} else if questionType == "boolean" {
-		answer, err := strconv.ParseBool(answer)
+
+		choice := choices[index-1]
+		err = question.Validate(questionKey, choice)
 		if err != nil {
-			return nil, err
+			color.HiRed("%v\n", err)
+			continue
 		}
-		return answer, nil
-	} else if questionType == "string" {
-		return answer, nil
-	} else {
-		return nil, errors.AnswerFormatError(fmt.Sprintf("could not convert %v to unknown %v type", answer, questionType))
+		return choice
 	}
+}
+
+func (e *CreateAction) queryMultiChoice(questionKey string, question config.Question, choices []interface{}) interface{} {
+	for {
+		fmt.Println(color.BlueString("Please select one or more values for `%s` - %s:", questionKey, question.Description))
+		for i, choice := range choices {
+			fmt.Printf("  %v) %v\n", i+1, choice)
+		}
+
+		answer := utils.StdinQuery(fmt.Sprintf("Enter numbers, comma separated [1-%v]:", len(choices)))
+		selected := []interface{}{}
+		valid := true
+		for _, selection := range strings.Split(answer, ",") {
+			selection = strings.TrimSpace(selection)
+			if len(selection) == 0 {
+				continue
+			}
+			index, err := utils.StringToInt(selection)
+			if err != nil || index < 1 || index > len(choices) {
+				color.HiRed("Invalid selection `%v`\n", selection)
+				valid = false
+				break
+			}
+			selected = append(selected, choices[index-1])
+		}
+		if !valid {
+			continue
+		}
 
+		err := question.Validate(questionKey, selected)
+		if err != nil {
+			color.HiRed("%v\n", err)
+			continue
+		}
+		return selected
+	}
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"drawbridge/pkg/actions"
 	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
 	"io/ioutil"
 	"os"
 )
@@ -33,3 +34,87 @@ func TestCreateAction_WriteAnswersFile(t *testing.T) {
 	//assert
 	require.NoError(t, err, "should not raise an error when adding writing answer file")
 }
+
+func TestCreateAction_Start_SecretBackendError(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configData, err := config.Create()
+	require.NoError(t, err)
+
+	parentPath, err := ioutil.TempDir("", "")
+	defer os.RemoveAll(parentPath)
+
+	configData.Set("options.config_dir", parentPath)
+	configData.Set("options.pem_dir", parentPath)
+	configData.Set("options.secret_backend", "unsupported")
+	configData.Set("questions", map[string]interface{}{
+		"environment": map[string]interface{}{"schema": map[string]interface{}{"type": "string", "required": true}},
+		"vault_token": map[string]interface{}{"secret": true, "schema": map[string]interface{}{"type": "string", "required": true}},
+	})
+	configData.Set("config_templates.default.filepath", "{{.environment}}")
+	createAction := actions.CreateAction{
+		Config: configData,
+	}
+
+	//test
+	err = createAction.Start(map[string]interface{}{"environment": "prod", "vault_token": "s.token"}, false)
+
+	//assert
+	require.IsType(t, errors.SecretBackendError(""), err, "should raise an error when the secret backend is unavailable")
+	configFiles, err := ioutil.ReadDir(parentPath)
+	require.NoError(t, err)
+	require.Empty(t, configFiles, "should not write the config file when the secrets cannot be stored")
+}
+
+func TestCreateAction_Query_ConditionalQuestions(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configData, err := config.Create()
+	require.NoError(t, err)
+
+	createAction := actions.CreateAction{
+		Config: configData,
+	}
+	questions := map[string]config.Question{
+		"environment": {Schema: map[string]interface{}{"type": "string", "required": true}},
+		"shard_type": {
+			Schema: map[string]interface{}{"type": "string", "required": true},
+			When:   `{{eq .environment "prod"}}`,
+		},
+	}
+
+	//test
+	answerData, err := createAction.Query(questions, map[string]interface{}{
+		"environment": "stage",
+		"shard_type":  "live",
+	})
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "stage", answerData["environment"])
+	require.Nil(t, answerData["shard_type"], "should ignore answers for questions that do not apply")
+}
+
+func TestCreateAction_Query_CircularDependency(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configData, err := config.Create()
+	require.NoError(t, err)
+
+	createAction := actions.CreateAction{
+		Config: configData,
+	}
+	questions := map[string]config.Question{
+		"environment": {Schema: map[string]interface{}{"type": "string"}, When: `{{.shard}}`},
+		"shard":       {Schema: map[string]interface{}{"type": "string"}, When: `{{.environment}}`},
+	}
+
+	//test
+	_, err = createAction.Query(questions, map[string]interface{}{})
+
+	//assert
+	require.IsType(t, errors.QuestionDependencyError(""), err, "should raise an error for circular dependencies")
+}
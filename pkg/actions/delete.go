@@ -2,6 +2,8 @@ package actions
 
 import (
 	"drawbridge/pkg/config"
+	"drawbridge/pkg/config/template"
+	"drawbridge/pkg/secret"
 	"drawbridge/pkg/utils"
 	"fmt"
 	"github.com/fatih/color"
@@ -70,6 +72,13 @@ func (e *DeleteAction) One(answerData map[string]interface{}, force bool) error
 			color.Yellow(" - Skipping. Could not find config file at: %v", renderedCustomFilePath)
 		}
 	}
+	//delete the drawbridge managed known_hosts file (if any host keys were recorded)
+	knownHostsFilePath := template.KnownHostsFilePath(renderedConfigFilePath)
+	if utils.FileExists(knownHostsFilePath) {
+		fmt.Printf("Deleting known_hosts file: %v\n", knownHostsFilePath)
+		utils.FileDelete(knownHostsFilePath)
+	}
+
 	//delete the .answers.yaml
 	fmt.Println("Deleting answers file")
 	answersFilePath := path.Join(answerData["config_dir"].(string), fmt.Sprintf(".%v.answers.yaml", path.Base(renderedConfigFilePath)))
@@ -79,5 +88,36 @@ func (e *DeleteAction) One(answerData map[string]interface{}, force bool) error
 		color.Yellow(" - Skipping. Could not find answers file at: %v", answersFilePath)
 	}
 
+	return e.deleteSecrets(renderedConfigFilePath, answerData)
+}
+
+// deleteSecrets removes the secret answers for this config from the secret backend.
+func (e *DeleteAction) deleteSecrets(renderedConfigFilePath string, answerData map[string]interface{}) error {
+	questions, err := e.Config.GetQuestions()
+	if err != nil {
+		return err
+	}
+
+	storedSecretKeys := []string{}
+	for _, secretKey := range config.SecretQuestionKeys(questions) {
+		if answerData[secretKey] == secret.Redacted {
+			storedSecretKeys = append(storedSecretKeys, secretKey)
+		}
+	}
+	if len(storedSecretKeys) == 0 {
+		return nil
+	}
+
+	secretBackend, err := e.Config.GetSecretBackend()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleting secrets (%v backend)\n", secretBackend.Name())
+	for _, secretKey := range storedSecretKeys {
+		err = secretBackend.Delete(secret.Id(renderedConfigFilePath, secretKey))
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -5,10 +5,8 @@ import (
 	"drawbridge/pkg/errors"
 	"drawbridge/pkg/utils"
 	"fmt"
-	"os"
 	"os/exec"
 	"path/filepath"
-	"syscall"
 )
 
 type DownloadAction struct {
@@ -17,6 +15,7 @@ type DownloadAction struct {
 }
 
 func (e *DownloadAction) Start(answerData map[string]interface{}, destHostname string, remoteFilePath string, localFilePath string) error {
+	defer e.closeAgent()
 
 	tmplData, err := e.Config.GetActiveConfigTemplate()
 	if err != nil {
@@ -36,7 +35,10 @@ func (e *DownloadAction) Start(answerData map[string]interface{}, destHostname s
 
 	//TODO: Print the lines we're running.
 
-	//TODO: Check that the bastion host is accessible.
+	err = e.Preflight(tmplConfigFilepath)
+	if err != nil {
+		return err
+	}
 
 	err = e.SshAgentAddPemKey(tmplPemFilepath)
 	if err != nil {
@@ -51,5 +53,5 @@ func (e *DownloadAction) Start(answerData map[string]interface{}, destHostname s
 
 	args := []string{"scp", "-F", tmplConfigFilepath, fmt.Sprintf("bastion+%v:%v", destHostname, remoteFilePath), localFilePath}
 
-	return syscall.Exec(scpBin, args, os.Environ())
+	return e.execWithAgent(scpBin, args)
 }
@@ -0,0 +1,71 @@
+package actions
+
+import (
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"github.com/fatih/color"
+	"os/exec"
+	"path/filepath"
+)
+
+type ExecAction struct {
+	ConnectAction
+	Config config.Interface
+}
+
+// Start runs the command on every internal host (through the bastion) concurrently. Each line of output is prefixed
+// with the host name. Returns an error if the command failed on any host.
+func (e *ExecAction) Start(answerData map[string]interface{}, destHostnames []string, command string, parallelism int) error {
+	defer e.closeAgent()
+
+	tmplData, err := e.Config.GetActiveConfigTemplate()
+	if err != nil {
+		return err
+	}
+
+	tmplConfigFilepath, err := utils.PopulatePathTemplate(filepath.Join(e.Config.GetString("options.config_dir"), tmplData.FilePath), answerData)
+	if err != nil {
+		return err
+	}
+
+	tmplPemFilepath, err := utils.PopulatePathTemplate(filepath.Join(e.Config.GetString("options.pem_dir"), tmplData.PemFilePath), answerData)
+	if err != nil {
+		return err
+	}
+
+	err = e.SshAgentAddPemKey(tmplPemFilepath)
+	if err != nil {
+		return err
+	}
+
+	sshBin, lookErr := exec.LookPath("ssh")
+	if lookErr != nil {
+		return errors.DependencyMissingError("ssh is missing")
+	}
+
+	fmt.Printf("Running `%v` on %v host(s) (parallelism: %v)\n", command, len(destHostnames), parallelism)
+
+	results := utils.ParallelExec(destHostnames, parallelism, func(destHostname string) error {
+		// BatchMode ensures that ssh fails rather than prompting, since we can't answer prompts for multiple hosts.
+		args := []string{"-F", tmplConfigFilepath, "-o", "BatchMode=yes", fmt.Sprintf("bastion+%v", destHostname), command}
+		return utils.CmdExec(sshBin, args, "", e.agentEnviron(), destHostname)
+	})
+
+	fmt.Println("\nSummary:")
+	failed := 0
+	for i, destHostname := range destHostnames {
+		if results[i] != nil {
+			failed++
+			fmt.Printf("%v: %v (%v)\n", destHostname, color.HiRedString("FAILED"), results[i])
+		} else {
+			fmt.Printf("%v: %v\n", destHostname, color.GreenString("OK"))
+		}
+	}
+
+	if failed > 0 {
+		return errors.RemoteCommandError(fmt.Sprintf("command failed on %v of %v host(s)", failed, len(destHostnames)))
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package actions
+
+import "golang.org/x/crypto/ssh/agent"
+
+// SshAgentClient & CloseAgent expose the ssh-agent helpers to the actions_test package.
+func (e *ConnectAction) SshAgentClient() (agent.ExtendedAgent, error) {
+	return e.sshAgentClient()
+}
+
+func (e *ConnectAction) CloseAgent() error {
+	return e.closeAgent()
+}
+
+// UploadArgs & UploadSize expose the upload helpers to the actions_test package.
+var UploadArgs = uploadArgs
+var UploadSize = uploadSize
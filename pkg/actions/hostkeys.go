@@ -0,0 +1,98 @@
+package actions
+
+import (
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/config/template"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/sshclient"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"github.com/fatih/color"
+	"golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"strings"
+)
+
+type HostKeysAction struct {
+	Config config.Interface
+}
+
+// List prints every host key recorded in the drawbridge managed known_hosts file for the selected config.
+func (e *HostKeysAction) List(answerData map[string]interface{}) error {
+	knownHostsFilePath := e.KnownHostsFilePath(answerData)
+
+	knownHostsList, err := sshclient.ListKnownHosts(knownHostsFilePath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Known hosts (%v):\n", knownHostsFilePath)
+	if len(knownHostsList) == 0 {
+		color.Yellow("\tNo host keys have been recorded")
+		return nil
+	}
+	for _, knownHost := range knownHostsList {
+		hosts := strings.Join(knownHost.Hosts, ",")
+		if knownHost.Hashed {
+			hosts = "(hashed)"
+		}
+		fmt.Printf("\t%v %v %v\n", color.YellowString(hosts), knownHost.KeyType, knownHost.Fingerprint)
+	}
+	return nil
+}
+
+// Forget removes the recorded host keys for a host (`host` or `host:port`), so the key will be trusted on next use.
+func (e *HostKeysAction) Forget(answerData map[string]interface{}, host string) error {
+	knownHostsFilePath := e.KnownHostsFilePath(answerData)
+
+	removed, err := sshclient.ForgetHost(knownHostsFilePath, host)
+	if err != nil {
+		return err
+	} else if removed == 0 {
+		return errors.InvalidArgumentsError(fmt.Sprintf("No host keys recorded for %v in %v", host, knownHostsFilePath))
+	}
+
+	color.Green("Removed %v host key(s) for %v", removed, host)
+	return nil
+}
+
+// Pin records the public key (read from a file, in authorized_keys or known_hosts format) as the only trusted key for
+// a host (`host` or `host:port`).
+func (e *HostKeysAction) Pin(answerData map[string]interface{}, host string, publicKeyFilePath string) error {
+	publicKeyFilePath, err := utils.ExpandPath(publicKeyFilePath)
+	if err != nil {
+		return err
+	}
+	publicKeyData, err := ioutil.ReadFile(publicKeyFilePath)
+	if err != nil {
+		return err
+	}
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(publicKeyData)
+	if err != nil {
+		// ssh-keyscan output (known_hosts format)
+		_, _, publicKey, _, _, err = ssh.ParseKnownHosts(publicKeyData)
+		if err != nil {
+			return errors.InvalidArgumentsError(fmt.Sprintf("Could not parse public key at %v", publicKeyFilePath))
+		}
+	}
+
+	knownHostsFilePath := e.KnownHostsFilePath(answerData)
+	err = sshclient.PinHost(knownHostsFilePath, host, publicKey)
+	if err != nil {
+		return err
+	}
+
+	color.Green("Pinned %v host key for %v (%v)", publicKey.Type(), host, ssh.FingerprintSHA256(publicKey))
+	return nil
+}
+
+// KnownHostsFilePath returns the drawbridge managed known_hosts file for the selected config. Answer files created
+// before known_hosts were managed by drawbridge don't contain the path, so it's derived from the config filepath.
+func (e *HostKeysAction) KnownHostsFilePath(answerData map[string]interface{}) string {
+	configData := answerData["config"].(map[string]interface{})
+	if knownHostsFilePath, ok := configData["known_hosts_filepath"].(string); ok && len(knownHostsFilePath) > 0 {
+		return knownHostsFilePath
+	}
+	return template.KnownHostsFilePath(configData["filepath"].(string))
+}
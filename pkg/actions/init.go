@@ -0,0 +1,323 @@
+package actions
+
+import (
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type InitAction struct {
+	Config config.Interface
+}
+
+// InitQuestion is a question defined using the `init` wizard.
+type InitQuestion struct {
+	Key          string
+	Description  string
+	Type         string
+	Enum         []interface{}
+	DefaultValue interface{}
+	Required     bool
+}
+
+// InitOptions are the (non-question) answers provided to the `init` wizard.
+type InitOptions struct {
+	BaseDomain      string
+	BastionHostname string
+	PacTemplate     bool
+}
+
+var initQuestionKeyPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Start walks the user through defining their questions & templates, and writes the generated config file.
+func (e *InitAction) Start(configFilePath string, force bool) error {
+	configFilePath, err := utils.ExpandPath(configFilePath)
+	if err != nil {
+		return err
+	}
+	// fail early, before asking any questions.
+	if utils.FileExists(configFilePath) && !force {
+		return errors.ConfigFileExistsError(fmt.Sprintf("A config file already exists at %v. Use --force to overwrite it", configFilePath))
+	}
+
+	color.Green("Creating a new drawbridge config file at %v\n", configFilePath)
+	fmt.Println("Questions are asked when running `drawbridge create`, and their answers are used to populate your templates.")
+
+	questions := e.queryQuestions()
+	if len(questions) == 0 {
+		return errors.InvalidArgumentsError("At least one question is required")
+	}
+
+	initOptions := InitOptions{}
+	initOptions.BaseDomain = queryWithDefault("What is the base domain of your infrastructure?", "example.com")
+	initOptions.BastionHostname = queryWithDefault(
+		"What is the hostname of your bastion? Use `{{.question_key}}` to reference answers, and `{{.vars.base_domain}}` for the base domain.",
+		fmt.Sprintf("bastion.{{.%v}}.{{.vars.base_domain}}", questions[0].Key),
+	)
+	initOptions.PacTemplate = utils.StdinQueryBoolean("Would you like to generate a Proxy auto-config (PAC) file template? [yes/no]:")
+
+	configContent, err := GenerateInitConfig(questions, initOptions)
+	if err != nil {
+		return err
+	}
+
+	err = e.WriteConfig(configFilePath, configContent, force)
+	if err != nil {
+		return err
+	}
+
+	color.Green("Config file written to %v. Run `drawbridge create` to create your first ssh config.", configFilePath)
+	return nil
+}
+
+// WriteConfig validates the generated config file content, before writing it to the configFilePath.
+func (e *InitAction) WriteConfig(configFilePath string, configContent string, force bool) error {
+	configFilePath, err := utils.ExpandPath(configFilePath)
+	if err != nil {
+		return err
+	}
+	if utils.FileExists(configFilePath) && !force {
+		return errors.ConfigFileExistsError(fmt.Sprintf("A config file already exists at %v. Use --force to overwrite it", configFilePath))
+	}
+
+	err = os.MkdirAll(filepath.Dir(configFilePath), 0700)
+	if err != nil {
+		return err
+	}
+
+	stagingFile, err := ioutil.TempFile(filepath.Dir(configFilePath), ".drawbridge-init")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stagingFile.Name())
+	_, err = stagingFile.WriteString(configContent)
+	stagingFile.Close()
+	if err != nil {
+		return err
+	}
+
+	err = e.Config.ValidateConfigFile(stagingFile.Name())
+	if err != nil {
+		return err
+	}
+	return os.Rename(stagingFile.Name(), configFilePath)
+}
+
+// GenerateInitConfig generates the content of a starter config file, with a config template (and optional PAC template)
+// that uses the questions.
+func GenerateInitConfig(questions []InitQuestion, initOptions InitOptions) (string, error) {
+	questionsContent := yaml.MapSlice{}
+	uiGroupPriority := []string{}
+	pathParts := []string{}
+	for _, question := range questions {
+		questionSchema := yaml.MapSlice{
+			{Key: "type", Value: question.Type},
+			{Key: "required", Value: question.Required},
+		}
+		if len(question.Enum) > 0 {
+			questionSchema = append(questionSchema, yaml.MapItem{Key: "enum", Value: question.Enum})
+		}
+
+		questionContent := yaml.MapSlice{{Key: "description", Value: question.Description}}
+		if question.DefaultValue != nil {
+			questionContent = append(questionContent, yaml.MapItem{Key: "default_value", Value: question.DefaultValue})
+		}
+		questionContent = append(questionContent, yaml.MapItem{Key: "schema", Value: questionSchema})
+		questionsContent = append(questionsContent, yaml.MapItem{Key: question.Key, Value: questionContent})
+
+		// ui_group_priority supports at most 4 questions.
+		if len(uiGroupPriority) < 4 {
+			uiGroupPriority = append(uiGroupPriority, question.Key)
+		}
+		// optional answers may be empty, they can't be used to generate a unique filepath.
+		if question.Required {
+			pathParts = append(pathParts, fmt.Sprintf("{{.%v}}", question.Key))
+		}
+	}
+	if len(pathParts) == 0 {
+		pathParts = append(pathParts, "bastion")
+	}
+
+	configTemplateContent := utils.StripIndent(fmt.Sprintf(
+		`
+		ForwardAgent yes
+		ForwardX11 no
+		HashKnownHosts no
+		IdentitiesOnly yes
+		StrictHostKeyChecking accept-new
+		UserKnownHostsFile {{.template.known_hosts_filepath}}
+
+		Host bastion
+		    Hostname %v
+		    IdentityFile {{.template.pem_filepath}}
+		    LocalForward localhost:{{uniquePort .template.filepath}} localhost:8080
+
+		Host bastion+*
+		    ProxyCommand ssh -F {{.template.filepath}} -W $(echo %%h |cut -d+ -f2):%%p bastion
+		    IdentityFile {{.template.pem_filepath}}
+		    LogLevel INFO
+		`, initOptions.BastionHostname))
+
+	configContent := yaml.MapSlice{
+		{Key: "version", Value: config.CurrentConfigVersion},
+		{Key: "options", Value: yaml.MapSlice{
+			{Key: "active_config_template", Value: "default"},
+			{Key: "ui_group_priority", Value: uiGroupPriority},
+		}},
+		{Key: "variables", Value: yaml.MapSlice{
+			{Key: "base_domain", Value: initOptions.BaseDomain},
+		}},
+		{Key: "questions", Value: questionsContent},
+		{Key: "config_templates", Value: yaml.MapSlice{
+			{Key: "default", Value: yaml.MapSlice{
+				{Key: "filepath", Value: strings.Join(pathParts, "-")},
+				{Key: "pem_filepath", Value: strings.Join(pathParts, "-") + ".pem"},
+				{Key: "content", Value: configTemplateContent},
+			}},
+		}},
+	}
+
+	if initOptions.PacTemplate {
+		pacTemplateContent := utils.StripIndent(fmt.Sprintf(
+			`
+			function FindProxyForURL(url, host){
+			    {{range .}}
+			    if(dnsDomainIs(host, ".%v")){
+			        return "PROXY localhost:{{uniquePort .config.filepath}}";
+			    }
+			    {{end}}
+
+			    // use default connection (skip proxy)
+			    return "DIRECT";
+			}
+			`, strings.Join(pathParts, ".")+".{{.vars.base_domain}}"))
+
+		configContent = append(configContent, yaml.MapItem{Key: "pac_template", Value: yaml.MapSlice{
+			{Key: "filepath", Value: "~/drawbridge.pac"},
+			{Key: "content", Value: pacTemplateContent},
+		}})
+	}
+
+	configYaml, err := yaml.Marshal(configContent)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("# Generated by `drawbridge init`. See example.drawbridge.yaml for all options.\n%s", configYaml), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+func (e *InitAction) queryQuestions() []InitQuestion {
+	questions := []InitQuestion{}
+	for {
+		questionKey := utils.StdinQuery("\nEnter a question key (lowercase letters, numbers & underscores, eg. `environment`). Leave empty to finish:")
+		if len(questionKey) == 0 {
+			return questions
+		}
+
+		if !initQuestionKeyPattern.MatchString(questionKey) {
+			color.HiRed("Invalid question key `%v`", questionKey)
+			continue
+		} else if utils.SliceIncludes(e.Config.InternalQuestionKeys(), questionKey) {
+			color.HiRed("`%v` is reserved by drawbridge, please choose a different key", questionKey)
+			continue
+		} else if initQuestionDefined(questions, questionKey) {
+			color.HiRed("`%v` has already been defined", questionKey)
+			continue
+		}
+
+		questions = append(questions, e.queryQuestion(questionKey))
+	}
+}
+
+func (e *InitAction) queryQuestion(questionKey string) InitQuestion {
+	question := InitQuestion{Key: questionKey}
+	question.Description = queryWithDefault(fmt.Sprintf("Enter a description for `%v`:", questionKey), fmt.Sprintf("What is the %v?", questionKey))
+
+	for {
+		question.Type = queryWithDefault("Enter the answer type [string, integer, number, boolean]:", "string")
+		if utils.SliceIncludes([]string{"string", "integer", "number", "boolean"}, question.Type) {
+			break
+		}
+		color.HiRed("Unsupported type `%v`", question.Type)
+	}
+
+	for {
+		question.Enum = []interface{}{}
+		enumValues := utils.StdinQuery("Enter the allowed values, comma separated. Leave empty to allow any value:")
+		if len(enumValues) == 0 {
+			break
+		}
+
+		valid := true
+		for _, enumValue := range strings.Split(enumValues, ",") {
+			enumValueTyped, err := config.ConvertAnswerType(strings.TrimSpace(enumValue), question.Type)
+			if err != nil {
+				color.HiRed("Invalid %v value `%v`: %v", question.Type, enumValue, err)
+				valid = false
+				break
+			}
+			question.Enum = append(question.Enum, enumValueTyped)
+		}
+		if valid {
+			break
+		}
+	}
+
+	for {
+		question.DefaultValue = nil
+		defaultValue := utils.StdinQuery("Enter a default value. Leave empty for no default:")
+		if len(defaultValue) == 0 {
+			break
+		}
+
+		defaultValueTyped, err := config.ConvertAnswerType(defaultValue, question.Type)
+		if err != nil {
+			color.HiRed("Invalid %v value `%v`: %v", question.Type, defaultValue, err)
+			continue
+		} else if len(question.Enum) > 0 && !initEnumIncludes(question.Enum, defaultValueTyped) {
+			color.HiRed("The default value must be one of the allowed values")
+			continue
+		}
+		question.DefaultValue = defaultValueTyped
+		break
+	}
+
+	question.Required = utils.StdinQueryBoolean(fmt.Sprintf("Is `%v` required? [yes/no]:", questionKey))
+	return question
+}
+
+func queryWithDefault(question string, defaultValue string) string {
+	answer := utils.StdinQuery(fmt.Sprintf("%v [%v]", question, defaultValue))
+	if len(answer) == 0 {
+		return defaultValue
+	}
+	return answer
+}
+
+func initQuestionDefined(questions []InitQuestion, questionKey string) bool {
+	for _, question := range questions {
+		if question.Key == questionKey {
+			return true
+		}
+	}
+	return false
+}
+
+func initEnumIncludes(enum []interface{}, value interface{}) bool {
+	for _, enumValue := range enum {
+		if enumValue == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package actions_test
+
+import (
+	"drawbridge/pkg/actions"
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestInitAction_WriteConfig(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configData, err := config.Create()
+	require.NoError(t, err)
+
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	configFilePath := path.Join(parentPath, "drawbridge.yaml")
+
+	configContent, err := actions.GenerateInitConfig([]actions.InitQuestion{
+		{Key: "environment", Description: "What is the environment?", Type: "string", Enum: []interface{}{"stage", "prod"}, DefaultValue: "stage", Required: true},
+		{Key: "region", Description: "What is the region?", Type: "string", Required: true},
+		{Key: "port", Description: "What is the port?", Type: "integer", Required: false},
+	}, actions.InitOptions{
+		BaseDomain:      "corp.example.com",
+		BastionHostname: "bastion.{{.region}}.{{.vars.base_domain}}",
+		PacTemplate:     true,
+	})
+	require.NoError(t, err)
+
+	initAction := actions.InitAction{
+		Config: configData,
+	}
+
+	//test
+	err = initAction.WriteConfig(configFilePath, configContent, false)
+
+	//assert
+	require.NoError(t, err, "should write a valid config file")
+
+	generatedConfig, err := config.Load([]string{configFilePath})
+	require.NoError(t, err, "should load the generated config file")
+	questions, err := generatedConfig.GetQuestions()
+	require.NoError(t, err)
+	require.Len(t, questions, 3, "should replace the default questions")
+	require.Equal(t, "{{.environment}}-{{.region}}", generatedConfig.GetString("config_templates.default.filepath"), "should use the required questions in the config filepath")
+	require.Equal(t, "corp.example.com", generatedConfig.GetString("variables.base_domain"))
+
+	issues, err := config.ValidateLayers([]string{configFilePath})
+	require.NoError(t, err)
+	require.Empty(t, issues, "generated templates should render")
+}
+
+func TestInitAction_WriteConfig_WhenConfigExists(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configData, err := config.Create()
+	require.NoError(t, err)
+
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	configFilePath := path.Join(parentPath, "drawbridge.yaml")
+	require.NoError(t, utils.FileWrite(configFilePath, "version: 1\n", 0644, false))
+
+	initAction := actions.InitAction{
+		Config: configData,
+	}
+
+	//test
+	err = initAction.WriteConfig(configFilePath, "version: 1\noptions:\n  pem_dir: '~/.ssh'\n", false)
+	forceErr := initAction.WriteConfig(configFilePath, "version: 1\noptions:\n  pem_dir: '~/.ssh'\n", true)
+
+	//assert
+	require.IsType(t, errors.ConfigFileExistsError(""), err, "should not overwrite an existing config file")
+	require.NoError(t, forceErr, "should overwrite an existing config file when forced")
+}
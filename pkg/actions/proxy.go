@@ -1,9 +1,31 @@
 package actions
 
-import "drawbridge/pkg/config"
+import (
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/project"
+	"drawbridge/pkg/secret"
+	"drawbridge/pkg/sshclient"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"github.com/fatih/color"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
 
 type ProxyAction struct {
+	ConnectAction
 	Config config.Interface
+
+	// reused when the PAC file is re-rendered, so the secrets file passphrase is only requested once.
+	secretBackend secret.Backend
 }
 
 func (e *ProxyAction) Start(answerDataList []map[string]interface{}, dryRun bool) error {
@@ -14,6 +36,15 @@ func (e *ProxyAction) Start(answerDataList []map[string]interface{}, dryRun bool
 		return err
 	}
 
+	answerDataList, err = e.withVariables(answerDataList)
+	if err != nil {
+		return err
+	}
+	answerDataList, err = e.withSecrets(answerDataList)
+	if err != nil {
+		return err
+	}
+
 	_, err = pacTemplate.WriteTemplate(answerDataList, dryRun)
 	if err != nil {
 		return err
@@ -21,3 +52,221 @@ func (e *ProxyAction) Start(answerDataList []map[string]interface{}, dryRun bool
 
 	return nil
 }
+
+// Serve runs a local SOCKS5 & HTTP proxy on the `uniquePort` for the selected drawbridge config, tunneling every
+// connection through the bastion. This is the port that the generated PAC file points to.
+func (e *ProxyAction) Serve(answerData map[string]interface{}) error {
+	defer e.closeAgent()
+	configData := answerData["config"].(map[string]interface{})
+	configFilepath := configData["filepath"].(string)
+
+	port, err := utils.UniquePort(configFilepath)
+	if err != nil {
+		return err
+	}
+
+	err = e.SshAgentAddPemKey(configData["pem_filepath"].(string))
+	if err != nil {
+		return err
+	}
+
+	sshConfig, err := sshclient.ParseConfigFile(configFilepath)
+	if err != nil {
+		return err
+	}
+
+	agentClient, err := e.sshAgentClient()
+	if err != nil {
+		return err
+	}
+
+	client, err := sshclient.Dial(sshConfig, "", agentClient)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", port))
+	if err != nil {
+		return err
+	}
+
+	// stop serving when the user exits, or the bastion connection is lost. The channel that is closed records which
+	// one happened.
+	interrupted := make(chan struct{})
+	disconnected := make(chan struct{})
+	var stopOnce sync.Once
+	stop := func(reason chan struct{}) {
+		stopOnce.Do(func() {
+			close(reason)
+			listener.Close()
+		})
+	}
+	go func() {
+		client.Wait()
+		stop(disconnected)
+	}()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	go func() {
+		<-interrupt
+		stop(interrupted)
+	}()
+
+	color.Green("Serving SOCKS5/HTTP proxy on localhost:%v through %v. Press Ctrl+C to stop.", port, client.HostConfig.Hostname())
+
+	proxyServer := sshclient.ProxyServer{Dial: client.Dial}
+	err = proxyServer.Serve(listener)
+	select {
+	case <-interrupted:
+		// the listener was closed on purpose, so the accept error is expected.
+		return nil
+	case <-disconnected:
+		return errors.TunnelDisconnectedError(fmt.Sprintf("the connection to %v was lost", client.HostConfig.Hostname()))
+	default:
+		listener.Close()
+		return err
+	}
+}
+
+// ServePac serves the rendered PAC file over http (at /drawbridge.pac). The PAC content is re-rendered whenever an
+// answers file in the config_dir is created, modified or deleted.
+func (e *ProxyAction) ServePac(listenAddress string) error {
+	pacContent, err := e.RenderPac()
+	if err != nil {
+		return err
+	}
+	answerFilesState, err := e.answerFilesState()
+	if err != nil {
+		return err
+	}
+
+	var pacMutex sync.RWMutex
+
+	go func() {
+		for range time.Tick(2 * time.Second) {
+			currentState, err := e.answerFilesState()
+			if err != nil || currentState == answerFilesState {
+				continue
+			}
+
+			updatedContent, err := e.RenderPac()
+			if err != nil {
+				color.HiRed("ERROR: could not re-render PAC file: %v", err)
+				continue
+			}
+			log.Printf("Answer files changed, re-rendered PAC file")
+
+			pacMutex.Lock()
+			pacContent = updatedContent
+			answerFilesState = currentState
+			pacMutex.Unlock()
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/drawbridge.pac", func(w http.ResponseWriter, r *http.Request) {
+		pacMutex.RLock()
+		defer pacMutex.RUnlock()
+
+		w.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+		w.Header().Set("Cache-Control", "no-cache")
+		fmt.Fprint(w, pacContent)
+	})
+
+	color.Green("Serving PAC file at http://%v/drawbridge.pac. Press Ctrl+C to stop.", listenAddress)
+	return http.ListenAndServe(listenAddress, mux)
+}
+
+// RenderPac renders the PAC template using the answers for every drawbridge managed config.
+func (e *ProxyAction) RenderPac() (string, error) {
+	projectList, err := project.CreateProjectListFromConfigDir(e.Config)
+	if err != nil {
+		return "", err
+	}
+
+	pacTemplate, err := e.Config.GetPacTemplate()
+	if err != nil {
+		return "", err
+	}
+
+	answerDataList, err := e.withVariables(projectList.GetAll())
+	if err != nil {
+		return "", err
+	}
+	answerDataList, err = e.withSecrets(answerDataList)
+	if err != nil {
+		return "", err
+	}
+
+	return pacTemplate.Render(answerDataList)
+}
+
+// withVariables (re-)resolves the config variables for every answer set, so that the PAC template always uses the
+// current variables, even for configs created before they were changed.
+func (e *ProxyAction) withVariables(answerDataList []map[string]interface{}) ([]map[string]interface{}, error) {
+	updatedAnswerDataList := []map[string]interface{}{}
+	for _, answerData := range answerDataList {
+		updatedAnswerData := map[string]interface{}{}
+		for k, v := range answerData {
+			updatedAnswerData[k] = v
+		}
+
+		vars, err := e.Config.GetVariables(answerData)
+		if err != nil {
+			return nil, err
+		}
+		updatedAnswerData["vars"] = vars
+		updatedAnswerDataList = append(updatedAnswerDataList, updatedAnswerData)
+	}
+	return updatedAnswerDataList, nil
+}
+
+// withSecrets loads the secret answers (redacted in the answers files) from the secret backend, so they're available
+// to the PAC template. The secret backend is only used when there are secret questions.
+func (e *ProxyAction) withSecrets(answerDataList []map[string]interface{}) ([]map[string]interface{}, error) {
+	questions, err := e.Config.GetQuestions()
+	if err != nil {
+		return nil, err
+	}
+	secretKeys := config.SecretQuestionKeys(questions)
+	if len(secretKeys) == 0 {
+		return answerDataList, nil
+	}
+
+	if e.secretBackend == nil {
+		e.secretBackend, err = e.Config.GetSecretBackend()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	updatedAnswerDataList := []map[string]interface{}{}
+	for _, answerData := range answerDataList {
+		updatedAnswerData, err := secret.Inject(e.secretBackend, answerData, secretKeys)
+		if err != nil {
+			return nil, err
+		}
+		updatedAnswerDataList = append(updatedAnswerDataList, updatedAnswerData)
+	}
+	return updatedAnswerDataList, nil
+}
+
+// answerFilesState is a summary of the paths, sizes and modification times for all answer files. Used to detect changes.
+func (e *ProxyAction) answerFilesState() (string, error) {
+	answerFiles, err := project.AnswerFilesInConfigDir(e.Config)
+	if err != nil {
+		return "", err
+	}
+
+	state := []string{}
+	for _, answerFile := range answerFiles {
+		info, err := os.Stat(answerFile)
+		if err != nil {
+			continue
+		}
+		state = append(state, fmt.Sprintf("%v:%v:%v", answerFile, info.Size(), info.ModTime().UnixNano()))
+	}
+	return strings.Join(state, "\n"), nil
+}
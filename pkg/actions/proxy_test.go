@@ -55,3 +55,61 @@ func TestProxyAction_Start(t *testing.T) {
 	require.NoError(t, err, "should not raise an error when generating pac file")
 	require.FileExists(t, filepath.Join(parentPath, "drawbridge.pac"))
 }
+
+func TestProxyAction_RenderPac(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configData, err := config.Create()
+	require.NoError(t, err)
+
+	parentPath, err := ioutil.TempDir("", "")
+	defer os.RemoveAll(parentPath)
+	drawbridgePath := path.Join(parentPath, "drawbridge")
+	err = utils.CopyDir(path.Join("testdata", "delete"), drawbridgePath)
+	require.NoError(t, err, "should not raise an error when copying test data")
+
+	configData.Set("options.config_dir", drawbridgePath)
+	configData.Set("pac_template.content", "{{range .}}{{.environment}}-{{.shard}};{{end}}")
+
+	proxyAction := actions.ProxyAction{
+		Config: configData,
+	}
+
+	//test
+	pacContent, err := proxyAction.RenderPac()
+
+	//assert
+	require.NoError(t, err, "should not raise an error when rendering pac file")
+	require.Equal(t, "prod-us-east-1;", pacContent, "should render pac file using answers in config dir")
+}
+
+func TestProxyAction_RenderPac_Variables(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configData, err := config.Create()
+	require.NoError(t, err)
+
+	parentPath, err := ioutil.TempDir("", "")
+	defer os.RemoveAll(parentPath)
+	drawbridgePath := path.Join(parentPath, "drawbridge")
+	err = utils.CopyDir(path.Join("testdata", "delete"), drawbridgePath)
+	require.NoError(t, err, "should not raise an error when copying test data")
+
+	configData.Set("options.config_dir", drawbridgePath)
+	configData.Set("variables.base_domain", "corp.example.net")
+	configData.Set("variables.internal_domain", "internal.{{.environment}}.{{.vars.base_domain}}")
+	configData.Set("pac_template.content", "{{range .}}{{.vars.internal_domain}};{{end}}")
+
+	proxyAction := actions.ProxyAction{
+		Config: configData,
+	}
+
+	//test
+	pacContent, err := proxyAction.RenderPac()
+
+	//assert
+	require.NoError(t, err, "should not raise an error when rendering pac file")
+	require.Equal(t, "internal.prod.corp.example.net;", pacContent, "should render pac file using config variables")
+}
@@ -0,0 +1,300 @@
+package actions
+
+import (
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/sshclient"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type TunnelAction struct {
+	ConnectAction
+	Config config.Interface
+
+	// UserConfigFilePath is the value of the global `--config` flag, which is forwarded to the tunnel daemon.
+	UserConfigFilePath string
+}
+
+// TunnelState is persisted to the config_dir by the tunnel daemon, and is used as its PID file.
+type TunnelState struct {
+	Pid       int            `yaml:"pid"`
+	StartedAt time.Time      `yaml:"started_at"`
+	Tunnels   []TunnelStatus `yaml:"tunnels"`
+}
+
+type TunnelStatus struct {
+	ConfigFilePath string    `yaml:"config_filepath"`
+	Forwards       []string  `yaml:"forwards"`
+	Status         string    `yaml:"status"`
+	LastError      string    `yaml:"last_error,omitempty"`
+	UpdatedAt      time.Time `yaml:"updated_at"`
+}
+
+// Up starts a background tunnel daemon, which keeps the bastion forwards open for every selected drawbridge config.
+func (e *TunnelAction) Up(answerDataList []map[string]interface{}) error {
+	if state, err := e.readState(); err == nil && processRunning(state.Pid) {
+		return errors.TunnelDaemonError(fmt.Sprintf("Tunnel daemon is already running (pid %v). Run `drawbridge tunnel down` first", state.Pid))
+	}
+
+	// the daemon is a separate process, so it can't share a private agent.
+	if len(os.Getenv("SSH_AUTH_SOCK")) == 0 {
+		return errors.DependencyMissingError("The tunnel daemon requires a running ssh-agent, but SSH_AUTH_SOCK is not set")
+	}
+
+	configFilePaths := []string{}
+	for _, answerData := range answerDataList {
+		configData := answerData["config"].(map[string]interface{})
+
+		// keys must be added in the foreground, the daemon cannot prompt for passphrases.
+		err := e.SshAgentAddPemKey(configData["pem_filepath"].(string))
+		if err != nil {
+			return err
+		}
+		configFilePaths = append(configFilePaths, configData["filepath"].(string))
+	}
+
+	executablePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	logFilePath, err := e.tunnelFilePath(".drawbridge-tunnel.log")
+	if err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	daemonArgs := []string{}
+	if len(e.UserConfigFilePath) > 0 {
+		// global flags must appear before the command.
+		daemonArgs = append(daemonArgs, "--config", e.UserConfigFilePath)
+	}
+	daemonArgs = append(daemonArgs, "tunnel", "run")
+	daemonCmd := exec.Command(executablePath, append(daemonArgs, configFilePaths...)...)
+	daemonCmd.Stdout = logFile
+	daemonCmd.Stderr = logFile
+	daemonCmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	err = daemonCmd.Start()
+	if err != nil {
+		return err
+	}
+
+	color.Green("Started tunnel daemon (pid %v) for %v config(s). Logs are written to %v", daemonCmd.Process.Pid, len(configFilePaths), logFilePath)
+	return daemonCmd.Process.Release()
+}
+
+// Down stops the background tunnel daemon.
+func (e *TunnelAction) Down() error {
+	state, err := e.readState()
+	if err != nil {
+		return errors.TunnelDaemonError("Tunnel daemon is not running")
+	}
+
+	if !processRunning(state.Pid) {
+		color.Yellow("Tunnel daemon (pid %v) is not running. Removing stale state file", state.Pid)
+		return e.removeState()
+	}
+
+	err = syscall.Kill(state.Pid, syscall.SIGTERM)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < 50 && processRunning(state.Pid); i++ {
+		time.Sleep(200 * time.Millisecond)
+	}
+	if processRunning(state.Pid) {
+		return errors.TunnelDaemonError(fmt.Sprintf("Tunnel daemon (pid %v) did not stop", state.Pid))
+	}
+
+	color.Green("Stopped tunnel daemon (pid %v)", state.Pid)
+	return nil
+}
+
+// Status prints the state of the tunnel daemon, and checks that every forwarded port is accepting connections.
+func (e *TunnelAction) Status() error {
+	state, err := e.readState()
+	if err != nil {
+		return errors.TunnelDaemonError("Tunnel daemon is not running")
+	}
+
+	if processRunning(state.Pid) {
+		fmt.Printf("Tunnel daemon: %v (pid %v, started %v)\n", color.GreenString("running"), state.Pid, state.StartedAt.Format(time.RFC1123))
+	} else {
+		fmt.Printf("Tunnel daemon: %v (stale pid %v)\n", color.HiRedString("stopped"), state.Pid)
+	}
+
+	for _, tunnel := range state.Tunnels {
+		statusColor := color.YellowString
+		if tunnel.Status == sshclient.TunnelStatusConnected {
+			statusColor = color.GreenString
+		}
+		fmt.Printf("\n%v\n\tstatus: %v (since %v)\n", tunnel.ConfigFilePath, statusColor(tunnel.Status), tunnel.UpdatedAt.Format(time.RFC1123))
+		if len(tunnel.LastError) > 0 {
+			fmt.Printf("\tlast error: %v\n", color.HiRedString(tunnel.LastError))
+		}
+
+		for _, forward := range tunnel.Forwards {
+			health := color.GreenString("healthy")
+			conn, err := net.DialTimeout("tcp", forward, time.Second)
+			if err != nil {
+				health = color.HiRedString("unhealthy")
+			} else {
+				conn.Close()
+			}
+			fmt.Printf("\tforward %v: %v\n", forward, health)
+		}
+	}
+	return nil
+}
+
+// Run is the tunnel daemon process (started by Up). It supervises a tunnel for each config file until it is stopped.
+func (e *TunnelAction) Run(configFilePaths []string) error {
+	agentClient, err := e.sshAgentClient()
+	if err != nil {
+		return err
+	}
+
+	state := TunnelState{Pid: os.Getpid(), StartedAt: time.Now()}
+	for _, configFilePath := range configFilePaths {
+		sshConfig, err := sshclient.ParseConfigFile(configFilePath)
+		if err != nil {
+			return err
+		}
+
+		tunnelStatus := TunnelStatus{
+			ConfigFilePath: configFilePath,
+			Forwards:       []string{},
+			Status:         sshclient.TunnelStatusConnecting,
+			UpdatedAt:      time.Now(),
+		}
+		for _, forward := range sshConfig.Host(sshclient.BastionHostAlias).GetAll("localforward") {
+			localAddress, _, err := sshclient.ParseForward(forward)
+			if err != nil {
+				return err
+			}
+			tunnelStatus.Forwards = append(tunnelStatus.Forwards, localAddress)
+		}
+		state.Tunnels = append(state.Tunnels, tunnelStatus)
+	}
+
+	err = e.writeState(state)
+	if err != nil {
+		return err
+	}
+	defer e.removeState()
+
+	var stateMutex sync.Mutex
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	for i := range state.Tunnels {
+		tunnelIndex := i
+		supervisor := sshclient.Supervisor{
+			ConfigFilePath: state.Tunnels[tunnelIndex].ConfigFilePath,
+			Agent:          agentClient,
+			OnStatusChange: func(status string, err error) {
+				stateMutex.Lock()
+				defer stateMutex.Unlock()
+
+				state.Tunnels[tunnelIndex].Status = status
+				state.Tunnels[tunnelIndex].UpdatedAt = time.Now()
+				if err != nil {
+					state.Tunnels[tunnelIndex].LastError = err.Error()
+				}
+				e.writeState(state)
+			},
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			supervisor.Run(stop)
+		}()
+	}
+
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+	<-shutdown
+
+	close(stop)
+	wg.Wait()
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+func (e *TunnelAction) tunnelFilePath(fileName string) (string, error) {
+	configDir, err := utils.ExpandPath(e.Config.GetString("options.config_dir"))
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(configDir, 0700)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, fileName), nil
+}
+
+func (e *TunnelAction) readState() (TunnelState, error) {
+	state := TunnelState{}
+
+	stateFilePath, err := e.tunnelFilePath(".drawbridge-tunnel.yaml")
+	if err != nil {
+		return state, err
+	}
+
+	stateContent, err := ioutil.ReadFile(stateFilePath)
+	if err != nil {
+		return state, err
+	}
+
+	err = yaml.Unmarshal(stateContent, &state)
+	return state, err
+}
+
+func (e *TunnelAction) writeState(state TunnelState) error {
+	stateFilePath, err := e.tunnelFilePath(".drawbridge-tunnel.yaml")
+	if err != nil {
+		return err
+	}
+
+	stateContent, err := yaml.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return utils.FileWrite(stateFilePath, string(stateContent), 0600, false)
+}
+
+func (e *TunnelAction) removeState() error {
+	stateFilePath, err := e.tunnelFilePath(".drawbridge-tunnel.yaml")
+	if err != nil {
+		return err
+	}
+	return os.Remove(stateFilePath)
+}
+
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	return syscall.Kill(pid, syscall.Signal(0)) == nil
+}
@@ -0,0 +1,56 @@
+package actions_test
+
+import (
+	"drawbridge/pkg/actions"
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestTunnelAction_Down_NotRunning(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configData, err := config.Create()
+	require.NoError(t, err)
+
+	parentPath, err := ioutil.TempDir("", "")
+	defer os.RemoveAll(parentPath)
+	configData.Set("options.config_dir", parentPath)
+
+	tunnelAction := actions.TunnelAction{
+		Config: configData,
+	}
+
+	//test
+	err = tunnelAction.Down()
+
+	//assert
+	require.Error(t, err, "should raise an error when tunnel daemon is not running")
+	require.IsType(t, errors.TunnelDaemonError(""), err, "should raise a tunnel daemon error")
+}
+
+func TestTunnelAction_Status_NotRunning(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configData, err := config.Create()
+	require.NoError(t, err)
+
+	parentPath, err := ioutil.TempDir("", "")
+	defer os.RemoveAll(parentPath)
+	configData.Set("options.config_dir", parentPath)
+
+	tunnelAction := actions.TunnelAction{
+		Config: configData,
+	}
+
+	//test
+	err = tunnelAction.Status()
+
+	//assert
+	require.Error(t, err, "should raise an error when tunnel daemon is not running")
+}
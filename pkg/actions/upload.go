@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+type UploadAction struct {
+	ConnectAction
+	Config config.Interface
+}
+
+func (e *UploadAction) Start(answerData map[string]interface{}, localFilePath string, destHostname string, remoteFilePath string) error {
+	defer e.closeAgent()
+
+	tmplData, err := e.Config.GetActiveConfigTemplate()
+	if err != nil {
+		return err
+	}
+
+	tmplConfigFilepath, err := utils.PopulatePathTemplate(filepath.Join(e.Config.GetString("options.config_dir"), tmplData.FilePath), answerData)
+	if err != nil {
+		return err
+	}
+
+	tmplPemFilepath, err := utils.PopulatePathTemplate(filepath.Join(e.Config.GetString("options.pem_dir"), tmplData.PemFilePath), answerData)
+	if err != nil {
+		return err
+	}
+
+	localFilePath, err = utils.ExpandPath(localFilePath)
+	if err != nil {
+		return err
+	}
+	localFileInfo, err := os.Stat(localFilePath)
+	if err != nil {
+		return errors.InvalidArgumentsError(fmt.Sprintf("Could not find local file to upload: %v", localFilePath))
+	}
+
+	err = e.SshAgentAddPemKey(tmplPemFilepath)
+	if err != nil {
+		return err
+	}
+
+	scpBin, lookErr := exec.LookPath("scp")
+	if lookErr != nil {
+		return errors.DependencyMissingError("scp is missing")
+	}
+
+	args := uploadArgs(tmplConfigFilepath, localFilePath, localFileInfo.IsDir(), destHostname, remoteFilePath)
+
+	fileCount, totalSize, err := uploadSize(localFilePath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Begin uploading %v file(s) (%v bytes) through bastion\n", fileCount, totalSize)
+
+	// scp will display a progress meter for each file, as long as we're attached to a terminal.
+	return e.execWithAgent(scpBin, args)
+}
+
+// uploadArgs returns the scp arguments, directories are uploaded recursively.
+func uploadArgs(configFilepath string, localFilePath string, recursive bool, destHostname string, remoteFilePath string) []string {
+	args := []string{"scp", "-F", configFilepath}
+	if recursive {
+		args = append(args, "-r")
+	}
+	return append(args, localFilePath, fmt.Sprintf("bastion+%v:%v", destHostname, remoteFilePath))
+}
+
+// uploadSize returns the number of files and their total size (in bytes) that will be uploaded.
+func uploadSize(localFilePath string) (int, int64, error) {
+	fileCount := 0
+	totalSize := int64(0)
+	err := filepath.Walk(localFilePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			fileCount++
+			totalSize += info.Size()
+		}
+		return nil
+	})
+	return fileCount, totalSize, err
+}
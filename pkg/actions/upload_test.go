@@ -0,0 +1,96 @@
+package actions_test
+
+import (
+	"drawbridge/pkg/actions"
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/utils"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestUploadAction_Start_MissingLocalFile(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configData, err := config.Create()
+	require.NoError(t, err)
+
+	uploadAction := actions.UploadAction{
+		Config: configData,
+	}
+
+	//test
+	err = uploadAction.Start(map[string]interface{}{
+		"environment": "prod",
+		"stack_name":  "app",
+		"shard":       "us-east-1",
+		"shard_type":  "idle",
+		"username":    "aws",
+	}, path.Join("testdata", "does_not_exist"), "database-1", "/tmp/")
+
+	//assert
+	require.Error(t, err, "should raise an error when local file is missing")
+}
+
+func TestUploadAction_Start_InvalidTemplate(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configData, err := config.Create()
+	require.NoError(t, err)
+
+	uploadAction := actions.UploadAction{
+		Config: configData,
+	}
+
+	//test
+	err = uploadAction.Start(map[string]interface{}{
+		"environment": "prod",
+	}, path.Join("testdata", "connect", "test_rsa.pem"), "database-1", "/tmp/")
+
+	//assert
+	require.Error(t, err, "should raise an error when the config filepath template cannot be rendered")
+}
+
+func TestUploadAction_UploadArgs_File(t *testing.T) {
+	t.Parallel()
+
+	//test
+	args := actions.UploadArgs("/drawbridge/prod-app", "/local/app.tar.gz", false, "database-1", "/tmp/")
+
+	//assert
+	require.Equal(t, []string{"scp", "-F", "/drawbridge/prod-app", "/local/app.tar.gz", "bastion+database-1:/tmp/"}, args, "should upload the file to the internal host through the bastion")
+}
+
+func TestUploadAction_UploadArgs_Directory(t *testing.T) {
+	t.Parallel()
+
+	//test
+	args := actions.UploadArgs("/drawbridge/prod-app", "/local/releases", true, "database-1", "/srv/releases")
+
+	//assert
+	require.Equal(t, []string{"scp", "-F", "/drawbridge/prod-app", "-r", "/local/releases", "bastion+database-1:/srv/releases"}, args, "should upload directories recursively")
+}
+
+func TestUploadAction_UploadSize_Directory(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	require.NoError(t, os.MkdirAll(path.Join(parentPath, "nested"), 0755))
+	require.NoError(t, utils.FileWrite(path.Join(parentPath, "app.conf"), "12345", 0644, false))
+	require.NoError(t, utils.FileWrite(path.Join(parentPath, "nested", "app.env"), "123", 0644, false))
+
+	//test
+	fileCount, totalSize, err := actions.UploadSize(parentPath)
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, 2, fileCount, "should count the files in nested directories")
+	require.Equal(t, int64(8), totalSize, "should sum the size of every file")
+}
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"drawbridge/pkg/config/template"
 	"drawbridge/pkg/errors"
+	"drawbridge/pkg/secret"
 	"drawbridge/pkg/utils"
 	"fmt"
+	"github.com/fatih/color"
 	"github.com/spf13/viper"
 	"github.com/xeipuuv/gojsonschema"
 	"gopkg.in/yaml.v2"
+	"io/ioutil"
 	"log"
 	"os"
 )
@@ -21,6 +24,11 @@ type configuration struct {
 	*viper.Viper
 }
 
+// defaultVariables are available to every template, unless they're overridden in a config file.
+var defaultVariables = map[string]string{
+	"base_domain": "example.com",
+}
+
 //Viper uses the following precedence order. Each item takes precedence over the item below it:
 // explicit call to Set
 // flag
@@ -39,6 +47,9 @@ func (c *configuration) Init() error {
 	c.SetDefault("options.active_custom_templates", []string{})
 	c.SetDefault("options.ui_group_priority", []string{"environment", "stack_name", "shard", "shard_type"})
 	c.SetDefault("options.ui_question_hidden", []string{})
+	c.SetDefault("options.agent_key_lifetime", 3600) //for safety we should limit the pem key's use in the ssh-agent to 1h
+	c.SetDefault("options.agent_key_confirm", false)
+	c.SetDefault("options.secret_backend", secret.BackendAuto)
 
 	c.SetDefault("questions", map[string]Question{
 		"environment": {
@@ -85,32 +96,34 @@ func (c *configuration) Init() error {
 		},
 	})
 	c.SetDefault("answers", []map[string]interface{}{})
+	c.SetDefault("variables", defaultVariables)
 	c.SetDefault("config_templates.default.pem_filepath", "{{.environment}}/{{.username}}-{{.environment}}.pem")
 	c.SetDefault("config_templates.default.filepath", `{{.environment}}-{{.stack_name}}-{{.shard_type}}-{{.shard}}{{if ne .username "aws"}}-{{.username}}{{end}}`)
+	// host names are not hashed in the drawbridge managed known_hosts file, so that `hostkeys list` can show them.
 	c.SetDefault("config_templates.default.content", utils.StripIndent(
 		`
 		ForwardAgent yes
 		ForwardX11 no
-		HashKnownHosts yes
+		HashKnownHosts no
 		IdentitiesOnly yes
-		StrictHostKeyChecking no
+		StrictHostKeyChecking accept-new
+		UserKnownHostsFile {{.template.known_hosts_filepath}}
 
 
 		Host bastion
-		  	Hostname bastion1.{{.shard_type}}.{{.shard}}.{{.stack_name}}{{if ne .environment "prod"}}{{.environment}}{{end}}example.com
+		  	Hostname bastion1.{{.shard_type}}.{{.shard}}.{{.stack_name}}{{if ne .environment "prod"}}{{.environment}}{{end}}{{.vars.base_domain}}
 		  	User {{if eq .username "aws"}}cloud-user{{else}}{{.username}}{{end}}
 		  	IdentityFile {{.template.pem_filepath}}
 		  	LocalForward localhost:{{uniquePort .template.filepath}} localhost:8080
-		  	UserKnownHostsFile=/dev/null
-		  	StrictHostKeyChecking=no
+		  	{{- if .template.proxy_jump}}
+		  	ProxyJump {{.template.proxy_jump}}
+		  	{{- end}}
 
 		Host bastion+*
 		  	ProxyCommand ssh -F {{.template.filepath}} -W $(echo %h |cut -d+ -f2):%p bastion
 		  	User {{if eq .username "aws"}}cloud-user{{else}}{{.username}}{{end}}
 		  	IdentityFile {{.template.pem_filepath}}
 		  	LogLevel INFO
-		  	UserKnownHostsFile=/dev/null
-		  	StrictHostKeyChecking=no
 	`))
 	c.SetDefault("custom_templates", map[string]interface{}{})
 
@@ -127,7 +140,7 @@ func (c *configuration) Init() error {
 			//determine if we need to use proxy. 
 
 			{{range .}}
-			if(dnsDomainIs(host, ".internal.{{.shard_type}}.{{.shard}}.{{.stack_name}}{{if ne .environment "prod"}}{{.environment}}{{end}}example.com")){
+			if(dnsDomainIs(host, ".internal.{{.shard_type}}.{{.shard}}.{{.stack_name}}{{if ne .environment "prod"}}{{.environment}}{{end}}{{.vars.base_domain}}")){
 				return "PROXY localhost:{{uniquePort .config.filepath}}";
 			}
 			{{end}}
@@ -166,13 +179,20 @@ func (c *configuration) ReadConfig(configFilePath string) error {
 
 	log.Printf("Loading configuration file: %s", configFilePath)
 
-	config_data, err := os.Open(configFilePath)
+	configContent, originalVersion, err := readConfigFile(configFilePath)
 	if err != nil {
-		log.Printf("Error reading configuration file: %s", err)
 		return err
 	}
+	if originalVersion < CurrentConfigVersion {
+		color.Yellow("WARNING: The configuration file at %v uses schema version %v, and was migrated to version %v in memory. Run `drawbridge config migrate` to update the file.", configFilePath, originalVersion, CurrentConfigVersion)
+	}
 
-	err = c.MergeConfig(config_data)
+	config_data, err := yaml.Marshal(configContent)
+	if err != nil {
+		return err
+	}
+
+	err = c.MergeConfig(bytes.NewReader(config_data))
 	if err != nil {
 		return err
 	}
@@ -206,38 +226,81 @@ func (c *configuration) ValidateConfig() error {
 }
 
 func (c *configuration) ValidateConfigFile(configFilePath string) error {
+	issues, err := configFileIssues(configFilePath)
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		errorMsg := ""
+		for _, issue := range issues {
+			errorMsg += fmt.Sprintf("- %v: %v\n", issue.Key, issue.Message)
+		}
+
+		return errors.ConfigValidationError(fmt.Sprintf("There was an error validating this config:\n %v ", errorMsg))
+	}
+	return nil
+}
+
+// configFileIssues validates a single config file against the config schema, and ensures that the template filepaths
+// are usable. Older config files are migrated (in memory) before validation.
+func configFileIssues(configFilePath string) ([]ValidationIssue, error) {
 	configFilePath, err := utils.ExpandPath(configFilePath)
 	if err != nil {
 		log.Printf("Could not expand filepath. %s", err)
-		return err
+		return nil, err
 	}
 
+	configContent, _, err := readConfigFile(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return configContentIssues(configFilePath, configContent)
+}
+
+// readConfigFile reads & parses a config file, migrating its content to the CurrentConfigVersion. The original schema
+// version of the file is returned.
+func readConfigFile(configFilePath string) (map[string]interface{}, int, error) {
 	configFileData, err := os.Open(configFilePath)
 	if err != nil {
 		log.Printf("Error reading configuration file: %s", err)
-		return err
+		return nil, 0, err
 	}
+	defer configFileData.Close()
 
 	buf := new(bytes.Buffer)
 	buf.ReadFrom(configFileData)
+	configContent, err := parseConfigContent(buf.Bytes())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	originalVersion, err := MigrateConfigContent(configContent)
+	if err != nil {
+		return nil, 0, err
+	}
+	return configContent, originalVersion, nil
+}
+
+func parseConfigContent(configFileData []byte) (map[string]interface{}, error) {
 	configContent := map[string]interface{}{}
-	err = yaml.Unmarshal(buf.Bytes(), &configContent)
+	err := yaml.Unmarshal(configFileData, &configContent)
+	if err != nil {
+		return nil, err
+	}
+
 	// To support boolean keys, the `yaml` package unmarshals maps to
 	// map[interface{}]interface{}. Here we recurse through the result
 	// and change all maps to map[string]interface{} like we would've
 	// gotten from `json`.
-	if err == nil {
-		for k, v := range configContent {
-			configContent[k] = utils.StringifyYAMLMapKeys(v)
-		}
-	} else {
-		return err
+	for k, v := range configContent {
+		configContent[k] = utils.StringifyYAMLMapKeys(v)
 	}
+	return configContent, nil
+}
 
-	// TODO: look at the dependencies key for matching the questions with answers keys.
-	// TODO: look at the dependenices key for matching the options.active_templates with templates keys
-	// TODO: ensure that all config_template.filepaths are relative, they will be created in the options.config_dir
-	// TODO: ensure that all custom_templates.filepaths are absolute or start with ~/
+func configContentIssues(configFilePath string, configContent map[string]interface{}) ([]ValidationIssue, error) {
+	// references between keys (eg. `options.active_config_template`) may be defined in other layers, they're checked
+	// against the merged config by ValidateLayers.
 	// language=json
 	configFileSchema := `
 	{
@@ -276,6 +339,38 @@ func (c *configuration) ValidateConfigFile(configFilePath string) error {
 						"type":"array",
 						"uniqueItems": true,
 						"items":[{"type":"string"}]
+					},
+					"agent_key_lifetime": {
+						"type": "integer",
+						"minimum": 0
+					},
+					"agent_key_confirm": {
+						"type": "boolean"
+					},
+					"secret_backend": {
+						"type": "string",
+						"enum": ["auto", "keyring", "file"]
+					},
+					"remote_config": {
+						"type": "object",
+						"additionalProperties": false,
+						"required": ["url"],
+						"properties": {
+							"type": {
+								"type": "string",
+								"enum": ["git", "http"]
+							},
+							"url": {
+								"type": "string",
+								"minLength": 1
+							},
+							"ref": {
+								"type": "string"
+							},
+							"path": {
+								"type": "string"
+							}
+						}
 					}
 				}
 			},
@@ -291,28 +386,77 @@ func (c *configuration) ValidateConfigFile(configFilePath string) error {
 								"type": "string"
 							},
 							"default_value": {},
+							"when": {
+								"type": "string"
+							},
+							"secret": {
+								"type": "boolean"
+							},
+							"choices_from": {
+								"type": "object",
+								"additionalProperties": false,
+								"minProperties": 1,
+								"maxProperties": 1,
+								"properties": {
+									"command": {
+										"type": "string"
+									},
+									"file": {
+										"type": "string"
+									}
+								}
+							},
+							"enum_when": {
+								"type": "array",
+								"items": {
+									"type": "object",
+									"additionalProperties": false,
+									"required": ["when", "enum"],
+									"properties": {
+										"when": {
+											"type": "string"
+										},
+										"enum": {
+											"type": "array",
+											"minItems": 1
+										}
+									}
+								}
+							},
 							"schema": {
 								"type": "object",
 								"additionalProperties":false,
 								"required": ["type"],
 								"properties": {
+									"additionalProperties": {},
 									"anyOf": {},
 									"enum": {},
 									"format": {},
+									"items": {
+										"type": "object"
+									},
+									"maxItems": {},
 									"maxLength": {},
 									"maximum": {},
+									"minItems": {},
 									"minLength": {},
 									"minimum": {},
 									"multipleOf": {},
 									"not": {},
 									"oneOf": {},
 									"pattern": {},
+									"properties": {
+										"type": "object"
+									},
 									"required": {
 										"type": "boolean"
 									},
 									"type": {
 										"type": "string",
-										"enum": ["integer", "number", "string", "boolean", "null"]
+										"enum": ["integer", "number", "string", "boolean", "array", "object", "null"]
+									},
+									"uniqueItems": {
+										"type": "boolean"
 									}
 								}
 							}
@@ -339,6 +483,7 @@ func (c *configuration) ValidateConfigFile(configFilePath string) error {
 						{
 							"type" : "object",
 							"additionalProperties":false,
+							"not": {"required": ["_file"]},
 							"patternProperties": {
 								"^[a-z0-9\\_]*$": {
 								}
@@ -350,7 +495,7 @@ func (c *configuration) ValidateConfigFile(configFilePath string) error {
 			"variables":{
 				"type": "object",
 				"patternProperties": {
-					"^[a-z0-9]*$":{
+					"^[a-z0-9_]*$":{
 						"type":"string"
 					}
 				}
@@ -361,8 +506,15 @@ func (c *configuration) ValidateConfigFile(configFilePath string) error {
 					"^[a-z0-9]*$":{
 						"type":"object",
 						"additionalProperties":false,
-						"required": ["filepath", "content", "pem_filepath"],
+						"anyOf": [
+							{"required": ["extends"]},
+							{"required": ["filepath", "content", "pem_filepath"]}
+						],
 						"properties": {
+							"extends": {
+								"type": "string",
+								"minLength": 1
+							},
 							"filepath": {
 								"type": "string"
 							},
@@ -371,6 +523,17 @@ func (c *configuration) ValidateConfigFile(configFilePath string) error {
 							},
 							"pem_filepath": {
 								"type": "string"
+							},
+							"agent_key_lifetime": {
+								"type": "integer",
+								"minimum": 0
+							},
+							"agent_key_confirm": {
+								"type": "boolean"
+							},
+							"jump_hosts": {
+								"type": "array",
+								"items": {"type": "string"}
 							}
 						}
 					}
@@ -394,6 +557,15 @@ func (c *configuration) ValidateConfigFile(configFilePath string) error {
 					}
 				}
 			},
+			"partials":{
+				"type": "object",
+				"additionalProperties": false,
+				"patternProperties": {
+					"^[a-z0-9_]+$":{
+						"type": "string"
+					}
+				}
+			},
 			"pac_template":{
 				"type":"object",
 				"additionalProperties":false,
@@ -416,30 +588,56 @@ func (c *configuration) ValidateConfigFile(configFilePath string) error {
 
 	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if !result.Valid() {
-		errorMsg := ""
-		for _, err := range result.Errors() {
-			// Err implements the ResultError interface
-			errorMsg += fmt.Sprintf("- %s\n", err)
-		}
 
-		return errors.ConfigValidationError(fmt.Sprintf("There was an error validating this config:\n %v ", errorMsg))
+	issues := []ValidationIssue{}
+	for _, err := range result.Errors() {
+		// Err implements the ResultError interface
+		issues = append(issues, ValidationIssue{FilePath: configFilePath, Key: err.Field(), Message: err.Description()})
 	}
-	return nil
+	if !result.Valid() {
+		return issues, nil
+	}
+
+	issues = append(issues, templateFilePathIssues(configFilePath, configContent)...)
+	return append(issues, configTemplateExtendsIssues(configFilePath, configContent)...), nil
 }
 
 func (c *configuration) InternalQuestionKeys() []string {
 	//list of internal keys, can be filtered out when printing, etc.
-	return []string{"config_dir", "pem_dir", "active_config_template", "active_custom_templates", "ui_group_priority", "ui_question_hidden", "custom", "config", "template"}
+	return []string{"config_dir", "pem_dir", "active_config_template", "active_custom_templates", "ui_group_priority", "ui_question_hidden", "agent_key_lifetime", "agent_key_confirm", "secret_backend", "remote_config", "custom", "config", "template", "vars"}
 }
 
 func (c *configuration) GetProvidedAnswerList() ([]map[string]interface{}, error) {
 	//deserialize
+	providedAnswerList := []map[string]interface{}{}
+	err := c.UnmarshalKey("answers", &providedAnswerList)
+	if err != nil {
+		return nil, err
+	}
+
+	questions, err := c.GetQuestions()
+	if err != nil {
+		return nil, err
+	}
+
+	//replace any `_file` entries with the answer sets in the referenced file
 	answerList := []map[string]interface{}{}
-	err := c.UnmarshalKey("answers", &answerList)
-	return answerList, err
+	for _, answerData := range providedAnswerList {
+		answersFilePath, ok := answerData["_file"].(string)
+		if !ok {
+			answerList = append(answerList, answerData)
+			continue
+		}
+
+		includedAnswerList, err := readAnswersFile(answersFilePath, questions)
+		if err != nil {
+			return nil, err
+		}
+		answerList = append(answerList, includedAnswerList...)
+	}
+	return answerList, nil
 }
 
 func (c *configuration) GetQuestion(questionKey string) (Question, error) {
@@ -464,11 +662,54 @@ func (c *configuration) GetQuestions() (map[string]Question, error) {
 	return questionsMap, err
 }
 
+func (c *configuration) GetVariables(answerData map[string]interface{}) (map[string]interface{}, error) {
+	//deserialize Variables
+	variables := map[string]string{}
+	err := c.UnmarshalKey("variables", &variables)
+	if err != nil {
+		return nil, err
+	}
+
+	// viper doesn't merge the default variables with the variables defined in the config files.
+	for variableKey, variableValue := range defaultVariables {
+		if _, ok := variables[variableKey]; !ok {
+			variables[variableKey] = variableValue
+		}
+	}
+	return ResolveVariables(variables, answerData)
+}
+
+func (c *configuration) GetRemoteConfig() (RemoteConfig, error) {
+	//deserialize RemoteConfig
+	remoteConfig := RemoteConfig{}
+	err := c.UnmarshalKey("options.remote_config", &remoteConfig)
+	return remoteConfig, err
+}
+
+func (c *configuration) GetSecretBackend() (secret.Backend, error) {
+	configDir, err := utils.ExpandPath(c.GetString("options.config_dir"))
+	if err != nil {
+		return nil, err
+	}
+	return secret.NewBackend(c.GetString("options.secret_backend"), configDir)
+}
+
+func (c *configuration) GetPartials() (map[string]string, error) {
+	//deserialize Partials
+	partials := map[string]string{}
+	err := c.UnmarshalKey("partials", &partials)
+	return partials, err
+}
+
 func (c *configuration) GetPacTemplate() (template.PacTemplate, error) {
 	//deserialize Template
 
 	template := template.PacTemplate{}
 	err := c.UnmarshalKey("pac_template", &template)
+	if err != nil {
+		return template, err
+	}
+	template.Partials, err = c.GetPartials()
 	return template, err
 }
 
@@ -476,7 +717,15 @@ func (c *configuration) GetConfigTemplates() (map[string]template.ConfigTemplate
 	//deserialize Templates
 	templateMap := map[string]template.ConfigTemplate{}
 	err := c.UnmarshalKey("config_templates", &templateMap)
-	return templateMap, err
+	if err != nil {
+		return nil, err
+	}
+
+	partials, err := c.GetPartials()
+	if err != nil {
+		return nil, err
+	}
+	return ResolveConfigTemplates(templateMap, partials)
 }
 
 func (c *configuration) GetActiveConfigTemplate() (template.ConfigTemplate, error) {
@@ -496,7 +745,19 @@ func (c *configuration) GetCustomTemplates() (map[string]template.FileTemplate,
 	//deserialize Templates
 	templateMap := map[string]template.FileTemplate{}
 	err := c.UnmarshalKey("custom_templates", &templateMap)
-	return templateMap, err
+	if err != nil {
+		return nil, err
+	}
+
+	partials, err := c.GetPartials()
+	if err != nil {
+		return nil, err
+	}
+	for templateName, customTemplate := range templateMap {
+		customTemplate.Partials = partials
+		templateMap[templateName] = customTemplate
+	}
+	return templateMap, nil
 }
 
 func (c *configuration) GetActiveCustomTemplates() ([]template.FileTemplate, error) {
@@ -515,3 +776,62 @@ func (c *configuration) GetActiveCustomTemplates() ([]template.FileTemplate, err
 	}
 	return activeTemplates, nil
 }
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+// readAnswersFile reads the answer set(s) from a YAML/JSON file, referenced by an `_file` answers entry. The file can
+// contain a single answer set (map) or a list of answer sets. Every answer is validated against its question.
+func readAnswersFile(answersFilePath string, questions map[string]Question) ([]map[string]interface{}, error) {
+	expandedFilePath, err := utils.ExpandPath(answersFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	answersFileData, err := ioutil.ReadFile(expandedFilePath)
+	if err != nil {
+		return nil, errors.AnswerFormatError(fmt.Sprintf("Could not read answers file %v: %v", answersFilePath, err))
+	}
+
+	// YAML is a superset of JSON, so this handles both formats.
+	var answersFileContent interface{}
+	err = yaml.Unmarshal(answersFileData, &answersFileContent)
+	if err != nil {
+		return nil, errors.AnswerFormatError(fmt.Sprintf("Could not parse answers file %v: %v", answersFilePath, err))
+	}
+
+	rawAnswerList := []interface{}{}
+	switch content := utils.StringifyYAMLMapKeys(answersFileContent).(type) {
+	case map[string]interface{}:
+		rawAnswerList = append(rawAnswerList, content)
+	case []interface{}:
+		rawAnswerList = content
+	default:
+		return nil, errors.AnswerFormatError(fmt.Sprintf("Answers file %v must contain an answer set (map) or a list of answer sets", answersFilePath))
+	}
+
+	answerList := []map[string]interface{}{}
+	for i, rawAnswerData := range rawAnswerList {
+		answerData, ok := rawAnswerData.(map[string]interface{})
+		if !ok {
+			return nil, errors.AnswerFormatError(fmt.Sprintf("Answer set #%v in %v must be a map", i+1, answersFilePath))
+		}
+
+		for answerKey, answerValue := range answerData {
+			if answerKey == "_file" {
+				return nil, errors.AnswerFormatError(fmt.Sprintf("Answer set #%v in %v cannot include another answers file", i+1, answersFilePath))
+			}
+
+			question, ok := questions[answerKey]
+			if !ok {
+				continue
+			}
+			err := question.Validate(answerKey, answerValue)
+			if err != nil {
+				return nil, errors.AnswerValidationError(fmt.Sprintf("Invalid answer for `%v` in answer set #%v of %v: %v", answerKey, i+1, answersFilePath, err))
+			}
+		}
+		answerList = append(answerList, answerData)
+	}
+	return answerList, nil
+}
@@ -2,7 +2,12 @@ package config_test
 
 import (
 	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
 	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
 	"path"
 	"testing"
 )
@@ -19,6 +24,8 @@ func TestConfiguration_init_ShouldCorrectlyInitializeConfiguration(t *testing.T)
 	require.Equal(t, "~/.ssh/drawbridge/pem", testConfig.GetString("options.pem_dir"), "should populate pem_dir with default")
 	require.Equal(t, "default", testConfig.GetString("options.active_config_template"), "should populate active_config_template with default")
 	require.Equal(t, []string{}, testConfig.GetStringSlice("options.active_custom_templates"), "should populate active_config_template with empty list")
+	require.Equal(t, 3600, testConfig.GetInt("options.agent_key_lifetime"), "should populate agent_key_lifetime with default")
+	require.False(t, testConfig.GetBool("options.agent_key_confirm"), "should populate agent_key_confirm with default")
 }
 
 func TestConfiguration_ReadConfig_InvalidFilePath(t *testing.T) {
@@ -161,3 +168,108 @@ func TestConfiguration_ReadConfig_OverrideDefaultConfigTemplate(t *testing.T) {
 	require.Equal(t, "{{.environment}}-{{.username}}", configTmpl.FilePath)
 
 }
+
+func TestConfiguration_GetVariables_MergesDefaultVariables(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	testConfig, _ := config.Create()
+	testConfig.Set("variables", map[string]string{"chef_domain": "chef.{{.environment}}.{{.vars.base_domain}}"})
+
+	//test
+	vars, err := testConfig.GetVariables(map[string]interface{}{"environment": "prod"})
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "example.com", vars["base_domain"], "should keep the default base_domain when variables are defined")
+	require.Equal(t, "chef.prod.example.com", vars["chef_domain"])
+}
+
+func TestConfiguration_ReadConfig_AgentKeyOptions(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	testConfig, _ := config.Create()
+
+	//test
+	err := testConfig.ReadConfig(path.Join("testdata", "valid_agent_key_options.yaml"))
+	require.NoError(t, err, "should allow setting agent key options")
+
+	configTmpl, err := testConfig.GetActiveConfigTemplate()
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, 900, testConfig.GetInt("options.agent_key_lifetime"), "should populate agent_key_lifetime with override")
+	require.True(t, testConfig.GetBool("options.agent_key_confirm"), "should populate agent_key_confirm with override")
+	require.Equal(t, 28800, *configTmpl.AgentKeyLifetime, "should populate config template agent_key_lifetime override")
+	require.Nil(t, configTmpl.AgentKeyConfirm, "should not populate config template agent_key_confirm when missing")
+}
+
+func TestConfiguration_ReadConfig_InvalidAgentKeyLifetime(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	testConfig, _ := config.Create()
+
+	//test
+	err := testConfig.ReadConfig(path.Join("testdata", "invalid_agent_key_lifetime.yaml"))
+
+	//assert
+	require.Error(t, err, "should return an error if the agent_key_lifetime is negative")
+}
+
+func TestConfiguration_GetProvidedAnswerList_AnswersFile(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+
+	singleAnswersFilePath := path.Join(parentPath, "single.yaml")
+	require.NoError(t, utils.FileWrite(singleAnswersFilePath, "environment: test\nusername: aws\n", 0644, false))
+	listAnswersFilePath := path.Join(parentPath, "list.json")
+	require.NoError(t, utils.FileWrite(listAnswersFilePath, `[{"environment": "stage"}, {"environment": "prod"}]`, 0644, false))
+
+	configFilePath := path.Join(parentPath, "drawbridge.yaml")
+	require.NoError(t, utils.FileWrite(configFilePath, fmt.Sprintf(
+		"version: 1\nanswers:\n- environment: test\n- _file: %v\n- _file: %v\n", singleAnswersFilePath, listAnswersFilePath,
+	), 0644, false))
+
+	testConfig, _ := config.Create()
+	require.NoError(t, testConfig.ReadConfig(configFilePath))
+
+	//test
+	answerList, err := testConfig.GetProvidedAnswerList()
+
+	//assert
+	require.NoError(t, err, "should load answers files")
+	require.Len(t, answerList, 4, "should replace `_file` entries with the answer sets in the referenced files")
+	require.Equal(t, "aws", answerList[1]["username"], "should load a single answer set")
+	require.Equal(t, "prod", answerList[3]["environment"], "should load a list of answer sets")
+}
+
+func TestConfiguration_GetProvidedAnswerList_InvalidAnswersFile(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+
+	answersFilePath := path.Join(parentPath, "invalid.yaml")
+	require.NoError(t, utils.FileWrite(answersFilePath, "- environment: test\n- environment: invalid\n", 0644, false))
+
+	configFilePath := path.Join(parentPath, "drawbridge.yaml")
+	require.NoError(t, utils.FileWrite(configFilePath, fmt.Sprintf("version: 1\nanswers:\n- _file: %v\n", answersFilePath), 0644, false))
+
+	testConfig, _ := config.Create()
+	require.NoError(t, testConfig.ReadConfig(configFilePath))
+
+	//test
+	_, err = testConfig.GetProvidedAnswerList()
+
+	//assert
+	require.IsType(t, errors.AnswerValidationError(""), err, "should validate answers in answers file")
+	require.Contains(t, err.Error(), answersFilePath, "should report which file the invalid answer came from")
+}
@@ -0,0 +1,8 @@
+package config
+
+// ConfigMigration, MigrateConfigContentWith & MigrateConfigFileWith expose the migration internals to the config_test
+// package, so that migrations can be tested with custom schema versions.
+type ConfigMigration = configMigration
+
+var MigrateConfigContentWith = migrateConfigContent
+var MigrateConfigFileWith = migrateConfigFile
@@ -7,3 +7,33 @@ func Create() (Interface, error) {
 	}
 	return config, nil
 }
+
+// Load creates a config, and merges each of the config file layers (lowest precedence first). If the config declares a
+// remote_config that has been synced, the cached remote config is merged beneath all other layers.
+func Load(configFileLayers []string) (Interface, error) {
+	config, err := loadLayers(configFileLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteConfigFilePath := remoteConfigLayer(config)
+	if len(remoteConfigFilePath) == 0 {
+		return config, nil
+	}
+
+	// start over, so that the local layers take precedence over the remote config.
+	return loadLayers(append([]string{remoteConfigFilePath}, configFileLayers...))
+}
+
+func loadLayers(configFileLayers []string) (Interface, error) {
+	config, err := Create()
+	if err != nil {
+		return nil, err
+	}
+	for _, configFilePath := range configFileLayers {
+		if err := config.ReadConfig(configFilePath); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
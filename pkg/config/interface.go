@@ -1,12 +1,16 @@
 package config
 
-import "drawbridge/pkg/config/template"
+import (
+	"drawbridge/pkg/config/template"
+	"drawbridge/pkg/secret"
+)
 
 // Create mock using:
 // mockgen -source=pkg/config/interface.go -destination=pkg/config/mock/mock_config.go
 type Interface interface {
 	Init() error
 	ReadConfig(configFilePath string) error
+	ValidateConfigFile(configFilePath string) error
 	Set(key string, value interface{})
 	SetDefault(key string, value interface{})
 	AllSettings() map[string]interface{}
@@ -25,6 +29,10 @@ type Interface interface {
 	//GetQuestionsSchema() (map[string]interface{}, error)
 	//GetQuestionSchema(question Question) (map[string]interface{}, error)
 
+	GetVariables(answerData map[string]interface{}) (map[string]interface{}, error)
+	GetRemoteConfig() (RemoteConfig, error)
+	GetSecretBackend() (secret.Backend, error)
+	GetPartials() (map[string]string, error)
 	GetPacTemplate() (template.PacTemplate, error)
 	GetConfigTemplates() (map[string]template.ConfigTemplate, error)
 	GetActiveConfigTemplate() (template.ConfigTemplate, error)
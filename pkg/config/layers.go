@@ -0,0 +1,85 @@
+package config
+
+import (
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	SystemConfigFilePath      = "/etc/drawbridge.yaml"
+	DefaultUserConfigFilePath = "~/drawbridge.yaml"
+	RepoConfigFileName        = ".drawbridge.yaml"
+	ConfigFileEnvVar          = "DRAWBRIDGE_CONFIG"
+)
+
+// ConfigFileLayers returns the config files that should be merged, lowest precedence first:
+// - system config file (/etc/drawbridge.yaml)
+// - user config file (--config flag, DRAWBRIDGE_CONFIG env var, or ~/drawbridge.yaml)
+// - repo-local config file (.drawbridge.yaml, found by walking up from the working directory)
+// Missing files are skipped, unless the user config file was explicitly specified.
+func ConfigFileLayers(systemConfigFilePath string, userConfigFlag string, workingDir string) ([]string, error) {
+	layers := []string{}
+
+	if utils.FileExists(systemConfigFilePath) {
+		layers = append(layers, systemConfigFilePath)
+	}
+
+	userConfigFilePath, explicit := UserConfigFilePath(userConfigFlag)
+	if explicit {
+		expandedPath, err := utils.ExpandPath(userConfigFilePath)
+		if err != nil {
+			return nil, err
+		}
+		if !utils.FileExists(expandedPath) {
+			return nil, errors.ConfigFileMissingError(fmt.Sprintf("The configuration file could not be found at %v", userConfigFilePath))
+		}
+		layers = append(layers, expandedPath)
+	} else if expandedPath, err := utils.ExpandPath(userConfigFilePath); err == nil && utils.FileExists(expandedPath) {
+		layers = append(layers, expandedPath)
+	}
+
+	repoConfigFilePath := findRepoConfigFile(workingDir)
+	if len(repoConfigFilePath) > 0 && !utils.SliceIncludes(layers, repoConfigFilePath) {
+		layers = append(layers, repoConfigFilePath)
+	}
+
+	return layers, nil
+}
+
+// UserConfigFilePath returns the path of the user config file (--config flag, DRAWBRIDGE_CONFIG env var, or
+// ~/drawbridge.yaml), and whether it was explicitly specified.
+func UserConfigFilePath(userConfigFlag string) (string, bool) {
+	if len(userConfigFlag) > 0 {
+		return userConfigFlag, true
+	} else if envConfigFilePath := os.Getenv(ConfigFileEnvVar); len(envConfigFilePath) > 0 {
+		return envConfigFilePath, true
+	}
+	return DefaultUserConfigFilePath, false
+}
+
+// findRepoConfigFile walks up from the working directory, and returns the path of the first repo-local config file found.
+func findRepoConfigFile(workingDir string) string {
+	if len(workingDir) == 0 {
+		return ""
+	}
+
+	currentDir, err := filepath.Abs(workingDir)
+	if err != nil {
+		return ""
+	}
+	for {
+		repoConfigFilePath := filepath.Join(currentDir, RepoConfigFileName)
+		if utils.FileExists(repoConfigFilePath) {
+			return repoConfigFilePath
+		}
+
+		parentDir := filepath.Dir(currentDir)
+		if parentDir == currentDir {
+			return ""
+		}
+		currentDir = parentDir
+	}
+}
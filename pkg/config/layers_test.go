@@ -0,0 +1,53 @@
+package config_test
+
+import (
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestConfigFileLayers(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+
+	systemConfigFilePath := path.Join(parentPath, "etc", "drawbridge.yaml")
+	userConfigFilePath := path.Join(parentPath, "home", "drawbridge.yaml")
+	repoConfigFilePath := path.Join(parentPath, "repo", ".drawbridge.yaml")
+	workingDir := path.Join(parentPath, "repo", "nested", "dir")
+	for _, filePath := range []string{systemConfigFilePath, userConfigFilePath, repoConfigFilePath} {
+		require.NoError(t, os.MkdirAll(path.Dir(filePath), 0777))
+		require.NoError(t, utils.FileWrite(filePath, "version: 1", 0644, false))
+	}
+	require.NoError(t, os.MkdirAll(workingDir, 0777))
+
+	//test
+	layers, err := config.ConfigFileLayers(systemConfigFilePath, userConfigFilePath, workingDir)
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, []string{systemConfigFilePath, userConfigFilePath, repoConfigFilePath}, layers, "should return layers in precedence order, lowest first")
+}
+
+func TestConfigFileLayers_MissingUserConfig(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+
+	//test
+	_, err = config.ConfigFileLayers(path.Join(parentPath, "drawbridge.yaml"), path.Join(parentPath, "missing.yaml"), parentPath)
+
+	//assert
+	require.IsType(t, errors.ConfigFileMissingError(""), err, "should raise an error when the specified user config is missing")
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"regexp"
+)
+
+// CurrentConfigVersion is the config file schema version supported by this drawbridge binary.
+const CurrentConfigVersion = 1
+
+// configMigration transforms a config file from one schema version to the next. Content transforms the parsed content
+// (in place). Text rewrites the affected nodes in the config file text, so that `config migrate` preserves the
+// comments & key order of hand-maintained files. The `version` key is updated separately.
+type configMigration struct {
+	Content func(configContent map[string]interface{}) error
+	Text    func(configText string) (string, error)
+}
+
+// configMigrations[v] migrates config files from version v to version v+1. When the config schema changes, bump the
+// CurrentConfigVersion and add a migration here. The `version` key has always been required, so there is no migration
+// for unversioned config files, they're rejected by the schema validation.
+var configMigrations = map[int]configMigration{}
+
+var configVersionLine = regexp.MustCompile(`(?m)^(version:[ \t]*)[0-9]+`)
+
+// MigrateConfigContent migrates config file content (in place) to the CurrentConfigVersion, and returns the original
+// schema version. Config files with a newer version than the CurrentConfigVersion cannot be migrated.
+func MigrateConfigContent(configContent map[string]interface{}) (int, error) {
+	return migrateConfigContent(configContent, configMigrations, CurrentConfigVersion)
+}
+
+// MigrateConfigFile rewrites an older config file using the CurrentConfigVersion schema. Only the `version` line & the
+// nodes changed by the migrations are modified. The original file is kept as a backup (`<filepath>.v<version>.bak`),
+// and its path is returned. Files that are already up to date are not modified.
+func MigrateConfigFile(configFilePath string) (int, string, error) {
+	return migrateConfigFile(configFilePath, configMigrations, CurrentConfigVersion)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+func migrateConfigContent(configContent map[string]interface{}, migrations map[int]configMigration, currentVersion int) (int, error) {
+	version, ok := configContent["version"].(int)
+	if !ok {
+		// missing & invalid versions are reported by the schema validation.
+		return currentVersion, nil
+	}
+
+	if version > currentVersion {
+		return version, errors.ConfigValidationError(fmt.Sprintf("The config file uses schema version %v, but this version of drawbridge only supports version %v. Please update drawbridge", version, currentVersion))
+	}
+
+	for migrationVersion := version; migrationVersion < currentVersion; migrationVersion++ {
+		migration, ok := migrations[migrationVersion]
+		if !ok {
+			return version, errors.ConfigValidationError(fmt.Sprintf("The config file uses schema version %v, which cannot be migrated", version))
+		}
+		if migration.Content != nil {
+			err := migration.Content(configContent)
+			if err != nil {
+				return version, errors.ConfigValidationError(fmt.Sprintf("Could not migrate the config file from version %v to version %v: %v", migrationVersion, migrationVersion+1, err))
+			}
+		}
+		configContent["version"] = migrationVersion + 1
+	}
+	return version, nil
+}
+
+func migrateConfigFile(configFilePath string, migrations map[int]configMigration, currentVersion int) (int, string, error) {
+	configFilePath, err := utils.ExpandPath(configFilePath)
+	if err != nil {
+		return 0, "", err
+	}
+
+	originalContent, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		return 0, "", err
+	}
+	configContent, err := parseConfigContent(originalContent)
+	if err != nil {
+		return 0, "", err
+	}
+	if _, ok := configContent["version"].(int); !ok {
+		return 0, "", errors.ConfigValidationError(fmt.Sprintf("%v does not specify a schema `version`", configFilePath))
+	}
+
+	originalVersion, err := migrateConfigContent(configContent, migrations, currentVersion)
+	if err != nil {
+		return originalVersion, "", err
+	}
+	if originalVersion == currentVersion {
+		return originalVersion, "", nil
+	}
+
+	migratedText := string(originalContent)
+	for migrationVersion := originalVersion; migrationVersion < currentVersion; migrationVersion++ {
+		if migrations[migrationVersion].Text == nil {
+			continue
+		}
+		migratedText, err = migrations[migrationVersion].Text(migratedText)
+		if err != nil {
+			return originalVersion, "", errors.ConfigValidationError(fmt.Sprintf("Could not migrate the config file from version %v to version %v: %v", migrationVersion, migrationVersion+1, err))
+		}
+	}
+	migratedText = configVersionLine.ReplaceAllString(migratedText, fmt.Sprintf("${1}%v", currentVersion))
+
+	// ensure that the rewritten file matches the migrated content, and is valid, before modifying anything.
+	migratedContent, err := parseConfigContent([]byte(migratedText))
+	if err != nil {
+		return originalVersion, "", err
+	}
+	if !reflect.DeepEqual(migratedContent, configContent) {
+		return originalVersion, "", errors.ConfigValidationError(fmt.Sprintf("Could not rewrite %v, the migrated file does not match the migrated config", configFilePath))
+	}
+	issues, err := configContentIssues(configFilePath, migratedContent)
+	if err != nil {
+		return originalVersion, "", err
+	}
+	if len(issues) > 0 {
+		return originalVersion, "", errors.ConfigValidationError(fmt.Sprintf("The migrated config file is invalid: %v", issues[0]))
+	}
+
+	backupFilePath := fmt.Sprintf("%v.v%v.bak", configFilePath, originalVersion)
+	err = utils.FileWrite(backupFilePath, string(originalContent), 0640, false)
+	if err != nil {
+		return originalVersion, "", err
+	}
+	log.Printf("Backed up %v to %v", configFilePath, backupFilePath)
+
+	err = utils.FileWrite(configFilePath, migratedText, 0640, false)
+	if err != nil {
+		return originalVersion, "", err
+	}
+	return originalVersion, backupFilePath, nil
+}
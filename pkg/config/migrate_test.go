@@ -0,0 +1,152 @@
+package config_test
+
+import (
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// testMigrations moves the pem_dir, in the config content & text.
+var testMigrations = map[int]config.ConfigMigration{
+	1: {
+		Content: func(configContent map[string]interface{}) error {
+			configContent["options"].(map[string]interface{})["pem_dir"] = "~/.ssh/pem"
+			return nil
+		},
+		Text: func(configText string) (string, error) {
+			return strings.Replace(configText, "'~/.ssh/drawbridge/pem'", "'~/.ssh/pem'", 1), nil
+		},
+	},
+}
+
+func TestMigrateConfigContent(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configContent := map[string]interface{}{
+		"version": 1,
+		"options": map[string]interface{}{"pem_dir": "~/.ssh/drawbridge/pem"},
+	}
+
+	//test
+	originalVersion, err := config.MigrateConfigContentWith(configContent, testMigrations, 2)
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, 1, originalVersion)
+	require.Equal(t, 2, configContent["version"], "should migrate to the current version")
+	require.Equal(t, "~/.ssh/pem", configContent["options"].(map[string]interface{})["pem_dir"], "should apply the migrations")
+}
+
+func TestMigrateConfigContent_NewerVersion(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configContent := map[string]interface{}{
+		"version": config.CurrentConfigVersion + 1,
+	}
+
+	//test
+	_, err := config.MigrateConfigContent(configContent)
+
+	//assert
+	require.IsType(t, errors.ConfigValidationError(""), err, "should not load config files from newer versions of drawbridge")
+}
+
+func TestMigrateConfigContent_UnsupportedVersion(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configContent := map[string]interface{}{
+		"version": 0,
+	}
+
+	//test
+	_, err := config.MigrateConfigContent(configContent)
+
+	//assert
+	require.IsType(t, errors.ConfigValidationError(""), err, "should not load config files without a migration")
+}
+
+func TestConfiguration_ReadConfig_UnversionedConfig(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	testConfig, _ := config.Create()
+
+	//test
+	err := testConfig.ReadConfig(path.Join("testdata", "invalid_unversioned_config.yaml"))
+
+	//assert
+	require.IsType(t, errors.ConfigValidationError(""), err, "should require the version key")
+}
+
+func TestMigrateConfigFile(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+
+	configFilePath := path.Join(parentPath, "drawbridge.yaml")
+	err = utils.CopyFile(path.Join("testdata", "valid_commented_config.yaml"), configFilePath)
+	require.NoError(t, err)
+
+	//test
+	originalVersion, backupFilePath, err := config.MigrateConfigFileWith(configFilePath, testMigrations, 2)
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, 1, originalVersion)
+	require.Equal(t, configFilePath+".v1.bak", backupFilePath, "should backup the original config file")
+	backupContent, err := ioutil.ReadFile(backupFilePath)
+	require.NoError(t, err)
+	originalContent, err := ioutil.ReadFile(path.Join("testdata", "valid_commented_config.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, string(originalContent), string(backupContent))
+
+	migratedContent, err := ioutil.ReadFile(configFilePath)
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimPrefix(utils.StripIndent(`
+		# Team drawbridge config, maintained by hand.
+		version: 2 # schema version
+
+		options:
+		  # keep the pem keys with the rest of the ssh config
+		  pem_dir: '~/.ssh/pem'
+		  config_dir: '~/.ssh/drawbridge'
+		`), "\n"), string(migratedContent), "should only rewrite the version & migrated nodes, keeping comments and key order")
+
+	//migrating again should be a no-op
+	_, backupFilePath, err = config.MigrateConfigFileWith(configFilePath, testMigrations, 2)
+	require.NoError(t, err)
+	require.Empty(t, backupFilePath, "should not migrate up to date config files")
+}
+
+func TestMigrateConfigFile_UpToDate(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+
+	configFilePath := path.Join(parentPath, "drawbridge.yaml")
+	err = utils.CopyFile(path.Join("testdata", "valid_commented_config.yaml"), configFilePath)
+	require.NoError(t, err)
+
+	//test
+	originalVersion, backupFilePath, err := config.MigrateConfigFile(configFilePath)
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, config.CurrentConfigVersion, originalVersion)
+	require.Empty(t, backupFilePath, "should not modify up to date config files")
+}
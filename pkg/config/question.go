@@ -2,14 +2,32 @@ package config
 
 import (
 	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
 	"fmt"
 	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v2"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+//`when` is a template condition (eg. `{{eq .environment "prod"}}`), the question is only asked when it renders `true`.
+//`enum_when` narrows the schema enum, using the first condition that renders `true`.
+//`choices_from` replaces the schema enum with a list loaded from a command or file.
+//`secret` answers are stored in the secret backend, and redacted everywhere else.
 type Question struct {
 	Description  string                 `mapstructure:"description"`
 	DefaultValue interface{}            `mapstructure:"default_value"`
 	Schema       map[string]interface{} `mapstructure:"schema"`
+	When         string                 `mapstructure:"when"`
+	EnumWhen     []QuestionEnumWhen     `mapstructure:"enum_when"`
+	ChoicesFrom  *QuestionChoicesFrom   `mapstructure:"choices_from"`
+	Secret       bool                   `mapstructure:"secret"`
+}
+
+type QuestionEnumWhen struct {
+	When string        `mapstructure:"when"`
+	Enum []interface{} `mapstructure:"enum"`
 }
 
 func (q *Question) GetType() string {
@@ -21,6 +39,58 @@ func (q *Question) Required() bool {
 	return isRequired && isSet
 }
 
+// ItemType returns the type of the items in an array answer (`schema.items.type`), defaults to string.
+func (q *Question) ItemType() string {
+	items, _ := utils.StringifyYAMLMapKeys(q.Schema["items"]).(map[string]interface{})
+	if itemType, ok := items["type"].(string); ok {
+		return itemType
+	}
+	return "string"
+}
+
+// ItemChoices returns the allowed items for an array answer (`schema.items.enum`).
+func (q *Question) ItemChoices() []interface{} {
+	items, _ := utils.StringifyYAMLMapKeys(q.Schema["items"]).(map[string]interface{})
+	return EnumChoices(items["enum"])
+}
+
+// ConvertAnswer converts a text answer (eg. provided via stdin or CLI flag) to the question type. Array answers are
+// comma separated, object answers use JSON/YAML flow syntax (eg. `{name: web, port: 8080}`).
+func (q *Question) ConvertAnswer(answer string) (interface{}, error) {
+	switch q.GetType() {
+	case "array":
+		values := []string{}
+		for _, value := range strings.Split(answer, ",") {
+			if value = strings.TrimSpace(value); len(value) > 0 {
+				values = append(values, value)
+			}
+		}
+		return q.ConvertArrayAnswer(values)
+	case "object":
+		objectAnswer := map[string]interface{}{}
+		err := yaml.Unmarshal([]byte(answer), &objectAnswer)
+		if err != nil {
+			return nil, errors.AnswerFormatError(fmt.Sprintf("could not convert %v to an object: %v", answer, err))
+		}
+		return utils.StringifyYAMLMapKeys(objectAnswer), nil
+	default:
+		return ConvertAnswerType(answer, q.GetType())
+	}
+}
+
+// ConvertArrayAnswer converts each text value (eg. repeated CLI flags) to the item type of an array question.
+func (q *Question) ConvertArrayAnswer(values []string) ([]interface{}, error) {
+	arrayAnswer := []interface{}{}
+	for _, value := range values {
+		item, err := ConvertAnswerType(value, q.ItemType())
+		if err != nil {
+			return nil, err
+		}
+		arrayAnswer = append(arrayAnswer, item)
+	}
+	return arrayAnswer, nil
+}
+
 func (q *Question) Validate(questionKey string, answerValue interface{}) error {
 	questionSchema := map[string]interface{}{
 		"properties": map[string]map[string]interface{}{
@@ -35,37 +105,20 @@ func (q *Question) Validate(questionKey string, answerValue interface{}) error {
 	}
 
 	//fix viper case-insensitivity & cleanup Schema
-	properRuleKeys := map[string]string{
-		"allof":             "allOf",
-		"anyof":             "anyOf",
-		"maxitems":          "maxItems",
-		"maxlength":         "maxLength",
-		"maxproperties":     "maxProperties",
-		"minitems":          "minItems",
-		"minlength":         "minLength",
-		"minproperties":     "minProperties",
-		"multipleof":        "multipleOf",
-		"oneof":             "oneOf",
-		"patternproperties": "patternProperties",
-		"uniqueitems":       "uniqueItems",
-	}
-
 	for ruleKey, ruleValue := range q.Schema {
 		if ruleKey == "required" {
 			//skip, required is already handled above.
 			continue
 		}
 
-		actualKey := ""
-		if val, ok := properRuleKeys[ruleKey]; ok {
-			//lets fix the rule key to use the uppercase version.
-			//fmt.Printf("\nSwitching %v for %v\n", actualKey, val)
-			actualKey = val
-		} else {
-			actualKey = ruleKey
-		}
+		questionSchema["properties"].(map[string]map[string]interface{})[questionKey][properSchemaRuleKey(ruleKey)] = fixSchemaRuleKeys(ruleValue)
+	}
 
-		questionSchema["properties"].(map[string]map[string]interface{})[questionKey][actualKey] = ruleValue
+	if q.ChoicesFrom != nil {
+		err := q.resolveChoices(questionSchema["properties"].(map[string]map[string]interface{})[questionKey])
+		if err != nil {
+			return err
+		}
 	}
 
 	schemaLoader := gojsonschema.NewGoLoader(questionSchema)
@@ -87,3 +140,280 @@ func (q *Question) Validate(questionKey string, answerValue interface{}) error {
 	}
 	return nil
 }
+
+// Dependencies returns the (sorted) keys referenced by the `when` and `enum_when` conditions.
+func (q *Question) Dependencies() ([]string, error) {
+	conditions := []string{q.When}
+	for _, enumWhen := range q.EnumWhen {
+		conditions = append(conditions, enumWhen.When)
+	}
+
+	dependencySet := map[string]bool{}
+	for _, condition := range conditions {
+		fields, err := templateFields(condition)
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range fields {
+			dependencySet[field[0]] = true
+		}
+	}
+
+	dependencies := []string{}
+	for dependency := range dependencySet {
+		dependencies = append(dependencies, dependency)
+	}
+	sort.Strings(dependencies)
+	return dependencies, nil
+}
+
+// Applicable evaluates the `when` condition. Questions without a condition are always applicable.
+func (q *Question) Applicable(answerData map[string]interface{}) (bool, error) {
+	if len(q.When) == 0 {
+		return true, nil
+	}
+	return q.evaluateCondition(q.When, answerData)
+}
+
+// ForAnswers returns a copy of the question, with the `choices_from` list resolved into the schema enum, then narrowed
+// by the first matching `enum_when` condition.
+func (q *Question) ForAnswers(answerData map[string]interface{}) (Question, error) {
+	narrowed := *q
+	narrowed.Schema = map[string]interface{}{}
+	for k, v := range q.Schema {
+		narrowed.Schema[k] = v
+	}
+
+	if q.ChoicesFrom != nil {
+		err := q.resolveChoices(narrowed.Schema)
+		if err != nil {
+			return narrowed, err
+		}
+		// already resolved, the command should not be run again.
+		narrowed.ChoicesFrom = nil
+	}
+
+	for _, enumWhen := range q.EnumWhen {
+		matches, err := q.evaluateCondition(enumWhen.When, answerData)
+		if err != nil {
+			return narrowed, err
+		}
+		if matches {
+			narrowed.Schema["enum"] = enumWhen.Enum
+			break
+		}
+	}
+	return narrowed, nil
+}
+
+// OrderQuestions sorts the question keys so that every question comes after the questions its conditions depend on.
+// Independent questions are sorted alphabetically.
+func OrderQuestions(questions map[string]Question) ([]string, error) {
+	questionKeys := []string{}
+	for questionKey := range questions {
+		questionKeys = append(questionKeys, questionKey)
+	}
+	sort.Strings(questionKeys)
+
+	ordered := []string{}
+	visited := map[string]bool{}
+	visiting := map[string]bool{}
+
+	var visit func(questionKey string, chain []string) error
+	visit = func(questionKey string, chain []string) error {
+		if visited[questionKey] {
+			return nil
+		}
+		chain = append(chain, questionKey)
+		if visiting[questionKey] {
+			return errors.QuestionDependencyError(fmt.Sprintf("Circular dependency between questions: %v", strings.Join(chain, " -> ")))
+		}
+
+		question := questions[questionKey]
+		dependencies, err := question.Dependencies()
+		if err != nil {
+			return errors.QuestionDependencyError(fmt.Sprintf("Question `%v` has an invalid condition: %v", questionKey, err))
+		}
+
+		visiting[questionKey] = true
+		for _, dependency := range dependencies {
+			// conditions may also reference options, they don't need to be ordered.
+			if _, ok := questions[dependency]; !ok {
+				continue
+			}
+			if err := visit(dependency, chain); err != nil {
+				return err
+			}
+		}
+		visiting[questionKey] = false
+
+		visited[questionKey] = true
+		ordered = append(ordered, questionKey)
+		return nil
+	}
+
+	for _, questionKey := range questionKeys {
+		if err := visit(questionKey, []string{}); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// resolveChoices sets the `choices_from` list as the schema enum. The choices of an array question are its allowed items.
+func (q *Question) resolveChoices(schema map[string]interface{}) error {
+	if q.GetType() != "array" {
+		choices, err := q.ChoicesFrom.Resolve(q.GetType())
+		if err != nil {
+			return err
+		}
+		schema["enum"] = choices
+		return nil
+	}
+
+	choices, err := q.ChoicesFrom.Resolve(q.ItemType())
+	if err != nil {
+		return err
+	}
+	items, ok := utils.StringifyYAMLMapKeys(schema["items"]).(map[string]interface{})
+	if !ok {
+		items = map[string]interface{}{"type": q.ItemType()}
+	}
+	items["enum"] = choices
+	schema["items"] = items
+	return nil
+}
+
+func (q *Question) evaluateCondition(condition string, answerData map[string]interface{}) (bool, error) {
+	dependencies, err := q.Dependencies()
+	if err != nil {
+		return false, err
+	}
+
+	// unanswered (optional) dependencies are treated as empty.
+	conditionData := map[string]interface{}{}
+	for _, dependency := range dependencies {
+		conditionData[dependency] = nil
+	}
+	for k, v := range answerData {
+		conditionData[k] = v
+	}
+
+	result, err := utils.PopulateTemplate(condition, conditionData)
+	if err != nil {
+		return false, errors.QuestionDependencyError(fmt.Sprintf("Could not evaluate condition `%v`: %v", condition, err))
+	}
+	return strings.TrimSpace(result) == "true", nil
+}
+
+// ConvertAnswerType converts a text answer to a scalar (string, integer, number or boolean) question type.
+func ConvertAnswerType(answer string, questionType string) (interface{}, error) {
+	if questionType == "integer" {
+		answer, err := strconv.ParseInt(answer, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return answer, nil
+	} else if questionType == "number" {
+		answer, err := strconv.ParseFloat(answer, 64)
+		if err != nil {
+			return nil, err
+		}
+		return answer, nil
+	} else if questionType == "boolean" {
+		answer, err := strconv.ParseBool(answer)
+		if err != nil {
+			return nil, err
+		}
+		return answer, nil
+	} else if questionType == "string" {
+		return answer, nil
+	} else {
+		return nil, errors.AnswerFormatError(fmt.Sprintf("could not convert %v to unknown %v type", answer, questionType))
+	}
+}
+
+// EnumChoices converts an enum (from the config, or set in code) to a list.
+func EnumChoices(enum interface{}) []interface{} {
+	switch enumList := enum.(type) {
+	case []interface{}:
+		return enumList
+	case []string:
+		choices := []interface{}{}
+		for _, choice := range enumList {
+			choices = append(choices, choice)
+		}
+		return choices
+	default:
+		return nil
+	}
+}
+
+// properRuleKeys maps the (lowercased by viper) JSON schema rule keys back to their proper case.
+var properRuleKeys = map[string]string{
+	"additionalitems":      "additionalItems",
+	"additionalproperties": "additionalProperties",
+	"allof":                "allOf",
+	"anyof":                "anyOf",
+	"maxitems":             "maxItems",
+	"maxlength":            "maxLength",
+	"maxproperties":        "maxProperties",
+	"minitems":             "minItems",
+	"minlength":            "minLength",
+	"minproperties":        "minProperties",
+	"multipleof":           "multipleOf",
+	"oneof":                "oneOf",
+	"patternproperties":    "patternProperties",
+	"uniqueitems":          "uniqueItems",
+}
+
+func properSchemaRuleKey(ruleKey string) string {
+	if val, ok := properRuleKeys[ruleKey]; ok {
+		return val
+	}
+	return ruleKey
+}
+
+// fixSchemaRuleKeys fixes the rule keys of nested schemas (eg. array `items` & object `properties`).
+func fixSchemaRuleKeys(ruleValue interface{}) interface{} {
+	switch nested := utils.StringifyYAMLMapKeys(ruleValue).(type) {
+	case map[string]interface{}:
+		fixed := map[string]interface{}{}
+		for key, value := range nested {
+			if key == "properties" || key == "patternproperties" {
+				//property names are not rule keys, but their values are schemas.
+				if propertySchemas, ok := value.(map[string]interface{}); ok {
+					fixedPropertySchemas := map[string]interface{}{}
+					for propertyName, propertySchema := range propertySchemas {
+						fixedPropertySchemas[propertyName] = fixSchemaRuleKeys(propertySchema)
+					}
+					value = fixedPropertySchemas
+				}
+			} else {
+				value = fixSchemaRuleKeys(value)
+			}
+			fixed[properSchemaRuleKey(key)] = value
+		}
+		return fixed
+	case []interface{}:
+		fixed := []interface{}{}
+		for _, value := range nested {
+			fixed = append(fixed, fixSchemaRuleKeys(value))
+		}
+		return fixed
+	default:
+		return ruleValue
+	}
+}
+
+// SecretQuestionKeys returns the (sorted) keys of the `secret` questions.
+func SecretQuestionKeys(questions map[string]Question) []string {
+	secretKeys := []string{}
+	for questionKey, question := range questions {
+		if question.Secret {
+			secretKeys = append(secretKeys, questionKey)
+		}
+	}
+	sort.Strings(secretKeys)
+	return secretKeys
+}
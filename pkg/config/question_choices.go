@@ -0,0 +1,104 @@
+package config
+
+import (
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+)
+
+// QuestionChoicesFrom loads the allowed answers for a question, either from the stdout lines of a local command (run
+// with `sh -c`), or from a JSON/YAML file containing a list.
+type QuestionChoicesFrom struct {
+	Command string `mapstructure:"command"`
+	File    string `mapstructure:"file"`
+
+	// the command may have side effects, so the choices are only loaded once (copies of a Question share them).
+	resolved bool
+	choices  []interface{}
+	err      error
+}
+
+// Resolve loads the choices, converted to the question type. The result is cached.
+func (c *QuestionChoicesFrom) Resolve(questionType string) ([]interface{}, error) {
+	if !c.resolved {
+		c.choices, c.err = c.load(questionType)
+		c.resolved = true
+	}
+	return c.choices, c.err
+}
+
+func (c *QuestionChoicesFrom) String() string {
+	if len(c.Command) > 0 {
+		return fmt.Sprintf("command `%v`", c.Command)
+	}
+	return fmt.Sprintf("file %v", c.File)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+func (c *QuestionChoicesFrom) load(questionType string) ([]interface{}, error) {
+	var choices []interface{}
+	var err error
+	if len(c.Command) > 0 {
+		choices, err = c.commandChoices(questionType)
+	} else if len(c.File) > 0 {
+		choices, err = c.fileChoices()
+	} else {
+		return nil, errors.QuestionChoicesError("`choices_from` must specify a `command` or `file`")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(choices) == 0 {
+		return nil, errors.QuestionChoicesError(fmt.Sprintf("No choices were loaded from %v", c))
+	}
+	return choices, nil
+}
+
+func (c *QuestionChoicesFrom) commandChoices(questionType string) ([]interface{}, error) {
+	output, err := exec.Command("sh", "-c", c.Command).Output()
+	if err != nil {
+		return nil, errors.QuestionChoicesError(fmt.Sprintf("Could not load choices from %v: %v", c, err))
+	}
+
+	choices := []interface{}{}
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		choice, err := ConvertAnswerType(line, questionType)
+		if err != nil {
+			return nil, errors.QuestionChoicesError(fmt.Sprintf("Invalid %v choice `%v` from %v: %v", questionType, line, c, err))
+		}
+		choices = append(choices, choice)
+	}
+	return choices, nil
+}
+
+func (c *QuestionChoicesFrom) fileChoices() ([]interface{}, error) {
+	choicesFilePath, err := utils.ExpandPath(c.File)
+	if err != nil {
+		return nil, err
+	}
+
+	choicesContent, err := ioutil.ReadFile(choicesFilePath)
+	if err != nil {
+		return nil, errors.QuestionChoicesError(fmt.Sprintf("Could not load choices from %v: %v", c, err))
+	}
+
+	// yaml is a superset of json.
+	choices := []interface{}{}
+	err = yaml.Unmarshal(choicesContent, &choices)
+	if err != nil {
+		return nil, errors.QuestionChoicesError(fmt.Sprintf("Could not parse choices from %v, it must contain a list: %v", c, err))
+	}
+	return choices, nil
+}
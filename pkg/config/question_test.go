@@ -2,7 +2,12 @@ package config_test
 
 import (
 	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
 	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
 	"path"
 	"testing"
 )
@@ -91,3 +96,281 @@ func TestQuestion_GetType(t *testing.T) {
 	require.NoError(t, err, "should not have an error")
 	require.Equal(t, "string", actual, "should correctly determine that `environment` is a string")
 }
+
+func TestOrderQuestions(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	questions := map[string]config.Question{
+		"environment": {Schema: map[string]interface{}{"type": "string"}},
+		"shard": {
+			Schema: map[string]interface{}{"type": "string"},
+			EnumWhen: []config.QuestionEnumWhen{
+				{When: `{{eq .environment "prod"}}`, Enum: []interface{}{"us-east-1"}},
+			},
+		},
+		"account": {
+			Schema: map[string]interface{}{"type": "string"},
+			When:   `{{and (eq .environment "prod") (eq .shard "us-east-1")}}`,
+		},
+		"username": {Schema: map[string]interface{}{"type": "string"}},
+	}
+
+	//test
+	questionKeys, err := config.OrderQuestions(questions)
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, []string{"environment", "shard", "account", "username"}, questionKeys, "should order questions after their dependencies")
+}
+
+func TestOrderQuestions_CircularDependency(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	questions := map[string]config.Question{
+		"environment": {Schema: map[string]interface{}{"type": "string"}, When: `{{eq .shard "us-east-1"}}`},
+		"shard":       {Schema: map[string]interface{}{"type": "string"}, When: `{{eq .environment "prod"}}`},
+	}
+
+	//test
+	_, err := config.OrderQuestions(questions)
+
+	//assert
+	require.IsType(t, errors.QuestionDependencyError(""), err, "should detect circular dependencies")
+	require.Contains(t, err.Error(), "environment -> shard -> environment", "should report the dependency chain")
+}
+
+func TestQuestion_Applicable(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	question := config.Question{
+		Schema: map[string]interface{}{"type": "string"},
+		When:   `{{eq .environment "prod"}}`,
+	}
+
+	//test
+	prodApplicable, prodErr := question.Applicable(map[string]interface{}{"environment": "prod"})
+	stageApplicable, stageErr := question.Applicable(map[string]interface{}{"environment": "stage"})
+
+	//assert
+	require.NoError(t, prodErr)
+	require.NoError(t, stageErr)
+	require.True(t, prodApplicable, "should be applicable when the condition is true")
+	require.False(t, stageApplicable, "should not be applicable when the condition is false")
+}
+
+func TestQuestion_ForAnswers(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	question := config.Question{
+		Schema: map[string]interface{}{"type": "string", "enum": []interface{}{"us-east-1", "us-east-2", "eu-west-1"}},
+		EnumWhen: []config.QuestionEnumWhen{
+			{When: `{{eq .environment "prod"}}`, Enum: []interface{}{"us-east-1", "eu-west-1"}},
+			{When: `{{eq .environment "stage"}}`, Enum: []interface{}{"us-east-2"}},
+		},
+	}
+
+	//test
+	prodQuestion, prodErr := question.ForAnswers(map[string]interface{}{"environment": "prod"})
+	testQuestion, testErr := question.ForAnswers(map[string]interface{}{"environment": "test"})
+
+	//assert
+	require.NoError(t, prodErr)
+	require.NoError(t, testErr)
+	require.Equal(t, []interface{}{"us-east-1", "eu-west-1"}, prodQuestion.Schema["enum"], "should narrow the enum using the first matching condition")
+	require.Equal(t, []interface{}{"us-east-1", "us-east-2", "eu-west-1"}, testQuestion.Schema["enum"], "should use the schema enum when no conditions match")
+	require.Equal(t, []interface{}{"us-east-1", "us-east-2", "eu-west-1"}, question.Schema["enum"], "should not modify the original question")
+}
+
+func TestQuestionChoicesFrom_Resolve_Command(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	choicesFrom := config.QuestionChoicesFrom{Command: "printf '1\\n\\n2\\n3\\n'"}
+
+	//test
+	choices, err := choicesFrom.Resolve("integer")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, choices, "should convert non-empty stdout lines to the question type")
+}
+
+func TestQuestionChoicesFrom_Resolve_CommandError(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	choicesFrom := config.QuestionChoicesFrom{Command: "exit 1"}
+
+	//test
+	_, err := choicesFrom.Resolve("string")
+
+	//assert
+	require.IsType(t, errors.QuestionChoicesError(""), err, "should raise an error when the command fails")
+}
+
+func TestQuestionChoicesFrom_Resolve_File(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	choicesFilePath := path.Join(parentPath, "regions.json")
+	require.NoError(t, utils.FileWrite(choicesFilePath, `["us-east-1", "eu-west-1"]`, 0644, false))
+
+	choicesFrom := config.QuestionChoicesFrom{File: choicesFilePath}
+
+	//test
+	choices, err := choicesFrom.Resolve("string")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"us-east-1", "eu-west-1"}, choices, "should load choices from a json/yaml list")
+}
+
+func TestQuestion_Validate_ChoicesFrom(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	question := config.Question{
+		Schema:      map[string]interface{}{"type": "string"},
+		ChoicesFrom: &config.QuestionChoicesFrom{Command: "echo us-east-1; echo eu-west-1"},
+	}
+
+	//test
+	validErr := question.Validate("shard", "eu-west-1")
+	invalidErr := question.Validate("shard", "ap-south-1")
+
+	//assert
+	require.NoError(t, validErr, "should accept answers in the resolved choices")
+	require.Error(t, invalidErr, "should reject answers that are not in the resolved choices")
+}
+
+func TestQuestion_Validate_ChoicesFromResolvedOnce(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	counterFilePath := path.Join(parentPath, "counter")
+
+	question := config.Question{
+		Schema:      map[string]interface{}{"type": "string"},
+		ChoicesFrom: &config.QuestionChoicesFrom{Command: fmt.Sprintf("echo run >> %v; echo us-east-1", counterFilePath)},
+	}
+
+	//test
+	require.NoError(t, question.Validate("region", "us-east-1"))
+	narrowedQuestion, err := question.ForAnswers(map[string]interface{}{})
+	require.NoError(t, err)
+	require.NoError(t, narrowedQuestion.Validate("region", "us-east-1"))
+	questionCopy := question
+	require.NoError(t, questionCopy.Validate("region", "us-east-1"))
+
+	//assert
+	counterContent, err := ioutil.ReadFile(counterFilePath)
+	require.NoError(t, err)
+	require.Equal(t, "run\n", string(counterContent), "should only run the choices command once per question")
+}
+
+func TestQuestion_ConvertAnswer_Array(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	question := config.Question{
+		Schema: map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+	}
+
+	//test
+	answer, err := question.ConvertAnswer("8080, 8443,")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(8080), int64(8443)}, answer, "should split comma separated items, and convert them to the item type")
+}
+
+func TestQuestion_ConvertAnswer_ArrayInvalidItem(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	question := config.Question{
+		Schema: map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+	}
+
+	//test
+	_, err := question.ConvertArrayAnswer([]string{"8080", "https"})
+
+	//assert
+	require.Error(t, err, "should fail when an item cannot be converted to the item type")
+}
+
+func TestQuestion_ChoicesFrom_Array(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	question := config.Question{
+		Schema:      map[string]interface{}{"type": "array", "items": map[interface{}]interface{}{"type": "integer"}},
+		ChoicesFrom: &config.QuestionChoicesFrom{Command: "echo 8080; echo 8443"},
+	}
+
+	//test
+	validErr := question.Validate("ports", []interface{}{8443})
+	invalidErr := question.Validate("ports", []interface{}{8080, 9000})
+	narrowedQuestion, err := question.ForAnswers(map[string]interface{}{})
+
+	//assert
+	require.NoError(t, validErr, "should accept arrays of the resolved choices")
+	require.Error(t, invalidErr, "should reject items that are not in the resolved choices")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(8080), int64(8443)}, narrowedQuestion.ItemChoices(), "should resolve the choices as the allowed items")
+	require.Nil(t, narrowedQuestion.Schema["enum"], "should not restrict the array itself")
+}
+
+func TestQuestion_ConvertAnswer_Object(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	question := config.Question{
+		Schema: map[string]interface{}{"type": "object"},
+	}
+
+	//test
+	answer, err := question.ConvertAnswer("{host: db1, port: 5432}")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"host": "db1", "port": 5432}, answer, "should parse objects using yaml/json flow syntax")
+}
+
+func TestQuestion_Validate_ArrayNestedRules(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	//viper lowercases nested keys too.
+	question := config.Question{
+		Schema: map[string]interface{}{
+			"type":        "array",
+			"minitems":    1,
+			"uniqueitems": true,
+			"items": map[interface{}]interface{}{
+				"type":      "string",
+				"maxlength": 5,
+			},
+		},
+	}
+
+	//test
+	validErr := question.Validate("services", []interface{}{"web", "api"})
+	emptyErr := question.Validate("services", []interface{}{})
+	itemErr := question.Validate("services", []interface{}{"web", "scheduler"})
+
+	//assert
+	require.NoError(t, validErr, "should accept valid arrays")
+	require.Error(t, emptyErr, "should enforce minItems")
+	require.Error(t, itemErr, "should enforce nested item rules")
+}
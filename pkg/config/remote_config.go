@@ -0,0 +1,68 @@
+package config
+
+import (
+	"drawbridge/pkg/utils"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	RemoteConfigTypeGit  = "git"
+	RemoteConfigTypeHttp = "http"
+
+	RemoteConfigCacheFileName = ".remote_config.yaml"
+	defaultRemoteConfigPath   = "drawbridge.yaml"
+)
+
+// RemoteConfig is a shared (team-wide) config file, stored in a git repository or served over http.
+// `drawbridge config sync` fetches it and caches it in the config_dir, where it's merged beneath the local config files.
+type RemoteConfig struct {
+	Type string `mapstructure:"type"`
+	Url  string `mapstructure:"url"`
+
+	// git only. Ref defaults to the default branch (HEAD), Path defaults to drawbridge.yaml
+	Ref  string `mapstructure:"ref"`
+	Path string `mapstructure:"path"`
+}
+
+// SourceType returns the configured type, or infers it from the url.
+func (r RemoteConfig) SourceType() string {
+	if len(r.Type) > 0 {
+		return r.Type
+	}
+	if strings.HasPrefix(r.Url, "http://") || strings.HasPrefix(r.Url, "https://") {
+		if strings.HasSuffix(r.Url, ".git") {
+			return RemoteConfigTypeGit
+		}
+		return RemoteConfigTypeHttp
+	}
+	return RemoteConfigTypeGit
+}
+
+func (r RemoteConfig) FilePath() string {
+	if len(r.Path) > 0 {
+		return r.Path
+	}
+	return defaultRemoteConfigPath
+}
+
+// RemoteConfigCacheFilePath returns the location of the cached remote config file in the config_dir.
+func RemoteConfigCacheFilePath(configDir string) (string, error) {
+	configDir, err := utils.ExpandPath(configDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, RemoteConfigCacheFileName), nil
+}
+
+// remoteConfigLayer returns the cached remote config file (if any), which should be merged beneath the local config files.
+func remoteConfigLayer(config Interface) string {
+	if len(config.GetString("options.remote_config.url")) == 0 {
+		return ""
+	}
+	cacheFilePath, err := RemoteConfigCacheFilePath(config.GetString("options.config_dir"))
+	if err != nil || !utils.FileExists(cacheFilePath) {
+		return ""
+	}
+	return cacheFilePath
+}
@@ -1,17 +1,26 @@
 package template
 
 import (
+	"drawbridge/pkg/secret"
 	"drawbridge/pkg/utils"
 	"fmt"
 	"github.com/fatih/color"
 	"path"
+	"strings"
 )
 
 // for configs `filepath`, must be relative to config_dir
 //for configs `pem_filepath` must be relative to pem_dir
+//`agent_key_lifetime` and `agent_key_confirm` override the global options, when set.
+//`jump_hosts` is the ordered list of hosts (outermost first, `[user@]host[:port]`) that must be traversed to reach the bastion.
+//`extends` is the name of the config template to inherit unset fields from.
 type ConfigTemplate struct {
-	FileTemplate `mapstructure:",squash"`
-	PemFilePath  string `mapstructure:"pem_filepath"`
+	FileTemplate     `mapstructure:",squash"`
+	Extends          string   `mapstructure:"extends"`
+	PemFilePath      string   `mapstructure:"pem_filepath"`
+	AgentKeyLifetime *int     `mapstructure:"agent_key_lifetime"`
+	AgentKeyConfirm  *bool    `mapstructure:"agent_key_confirm"`
+	JumpHosts        []string `mapstructure:"jump_hosts"`
 }
 
 func (t *ConfigTemplate) DeleteTemplate(answerData map[string]interface{}) error {
@@ -19,7 +28,14 @@ func (t *ConfigTemplate) DeleteTemplate(answerData map[string]interface{}) error
 	return t.FileTemplate.DeleteTemplate(answerData)
 }
 
-func (t *ConfigTemplate) WriteTemplate(answerData map[string]interface{}, ignoreKeys []string, dryRun bool) (map[string]interface{}, error) {
+// ConfigFilePath renders the path of the config file (in the config_dir), without writing it.
+func (t *ConfigTemplate) ConfigFilePath(answerData map[string]interface{}) (string, error) {
+	return utils.PopulatePathTemplate(path.Join(answerData["config_dir"].(string), t.FilePath), answerData)
+}
+
+// WriteTemplate renders the config file, prefixed with a header listing the answers. ignoreKeys are skipped, and
+// secretKeys are redacted in the header.
+func (t *ConfigTemplate) WriteTemplate(answerData map[string]interface{}, ignoreKeys []string, secretKeys []string, dryRun bool) (map[string]interface{}, error) {
 	//intialize template data.
 	if t.data == nil {
 		t.data = map[string]interface{}{}
@@ -39,6 +55,19 @@ func (t *ConfigTemplate) WriteTemplate(answerData map[string]interface{}, ignore
 	}
 
 	t.data["pem_filepath"] = templatedPemFilePath
+
+	//populate the jump host chain, available in the content as a list and in ProxyJump format
+	jumpHosts := []string{}
+	for _, jumpHost := range t.JumpHosts {
+		templatedJumpHost, err := utils.PopulateTemplate(jumpHost, answerData)
+		if err != nil {
+			return nil, err
+		}
+		jumpHosts = append(jumpHosts, templatedJumpHost)
+	}
+	t.data["jump_hosts"] = jumpHosts
+	t.data["proxy_jump"] = strings.Join(jumpHosts, ",")
+
 	answerData["template"] = t.data
 
 	if !utils.FileExists(templatedPemFilePath) {
@@ -46,7 +75,15 @@ func (t *ConfigTemplate) WriteTemplate(answerData map[string]interface{}, ignore
 	}
 
 	t.FilePath = path.Join(answerData["config_dir"].(string), t.FilePath)
-	t.Content = configTemplatePrefix(answerData, ignoreKeys) + t.Content
+
+	//each config has its own drawbridge managed known_hosts file, alongside the config file.
+	templatedFilePath, err := utils.PopulatePathTemplate(t.FilePath, answerData)
+	if err != nil {
+		return nil, err
+	}
+	t.data["known_hosts_filepath"] = KnownHostsFilePath(templatedFilePath)
+
+	t.Content = configTemplatePrefix(answerData, ignoreKeys, secretKeys) + t.Content
 
 	_, err = t.FileTemplate.WriteTemplate(answerData, dryRun)
 	if err != nil {
@@ -57,7 +94,12 @@ func (t *ConfigTemplate) WriteTemplate(answerData map[string]interface{}, ignore
 
 }
 
-func configTemplatePrefix(answerData map[string]interface{}, ignoreKeys []string) string {
+// KnownHostsFilePath returns the path of the drawbridge managed known_hosts file for a (rendered) config file path.
+func KnownHostsFilePath(configFilePath string) string {
+	return path.Join(path.Dir(configFilePath), fmt.Sprintf(".%v.known_hosts", path.Base(configFilePath)))
+}
+
+func configTemplatePrefix(answerData map[string]interface{}, ignoreKeys []string, secretKeys []string) string {
 	prefix := utils.StripIndent(
 		`
 		# This file was automatically generated by Drawbridge
@@ -71,6 +113,9 @@ func configTemplatePrefix(answerData map[string]interface{}, ignoreKeys []string
 		if utils.SliceIncludes(ignoreKeys, key) {
 			continue
 		}
+		if value != nil && utils.SliceIncludes(secretKeys, key) {
+			value = secret.Redacted
+		}
 
 		prefix += fmt.Sprintf("\n# %v = %v", key, value)
 	}
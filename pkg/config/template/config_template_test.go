@@ -66,12 +66,13 @@ func TestConfigTemplate_WriteTemplate(t *testing.T) {
 		"example":    "1",
 		"config_dir": parentPath,
 		"pem_dir":    parentPath,
-	}, []string{}, false)
+	}, []string{}, []string{}, false)
 
 	//assert
 	require.NoError(t, err, "should not raise an error deleting filepath template")
 	require.FileExists(t, actual["filepath"].(string), "test file should written ")
 	require.Equal(t, testFilePath, actual["filepath"].(string), "test file path be set correctly")
+	require.Equal(t, path.Join(parentPath, ".1.text.known_hosts"), actual["known_hosts_filepath"].(string), "known_hosts file path should be set correctly")
 }
 
 func TestConfigTemplate_WriteTemplate_ShouldGenerateValidPrefix(t *testing.T) {
@@ -98,7 +99,7 @@ func TestConfigTemplate_WriteTemplate_ShouldGenerateValidPrefix(t *testing.T) {
 		"example":    "1",
 		"config_dir": parentPath,
 		"pem_dir":    parentPath,
-	}, []string{"example"}, false)
+	}, []string{"example"}, []string{}, false)
 	require.NoError(t, err, "should not raise an error when writing file")
 	actualContent, err := ioutil.ReadFile(testFilePath)
 	require.NoError(t, err, "should not raise an error when reading file")
@@ -113,8 +114,8 @@ func TestConfigTemplate_WriteTemplate_ShouldGenerateValidPrefix(t *testing.T) {
 	# Answers:
 	# config_dir = %s
 	# pem_dir = %s
-	# template = map[pem_filepath:%s/1.pem]
-	config content`, parentPath, parentPath,parentPath)), string(actualContent), "test file prefix & content should match, and skip example key")
+	# template = map[jump_hosts:[] known_hosts_filepath:%s/.1.text.known_hosts pem_filepath:%s/1.pem proxy_jump:]
+	config content`, parentPath, parentPath,parentPath,parentPath)), string(actualContent), "test file prefix & content should match, and skip example key")
 }
 
 //
@@ -176,3 +177,69 @@ func TestConfigTemplate_WriteTemplate_ShouldGenerateValidPrefix(t *testing.T) {
 //	//assert
 //	require.Error(t, err,"should raise an error if destination file already exists.")
 //}
+
+func TestConfigTemplate_WriteTemplate_JumpHosts(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	defer os.RemoveAll(parentPath)
+
+	fileTemplate := template.ConfigTemplate{
+		PemFilePath: "{{.example}}.pem",
+		JumpHosts:   []string{"gateway.example.com", "{{.example}}@regional.example.com:2222"},
+		FileTemplate: template.FileTemplate{
+			FilePath: "{{.example}}.text",
+			Template: template.Template{
+				Content: "ProxyJump {{.template.proxy_jump}}",
+			},
+		},
+	}
+
+	//test
+	actual, err := fileTemplate.WriteTemplate(map[string]interface{}{
+		"example":    "1",
+		"config_dir": parentPath,
+		"pem_dir":    parentPath,
+	}, []string{}, []string{}, false)
+	require.NoError(t, err, "should not raise an error writing template")
+	content, err := ioutil.ReadFile(actual["filepath"].(string))
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, []string{"gateway.example.com", "1@regional.example.com:2222"}, actual["jump_hosts"], "should populate jump hosts")
+	require.Contains(t, string(content), "ProxyJump gateway.example.com,1@regional.example.com:2222", "should render jump chain in ProxyJump format")
+}
+
+func TestConfigTemplate_WriteTemplate_ShouldRedactSecretsInPrefix(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	defer os.RemoveAll(parentPath)
+
+	fileTemplate := template.ConfigTemplate{
+		PemFilePath: "{{.example}}.pem",
+		FileTemplate: template.FileTemplate{
+			FilePath: "{{.example}}.text",
+			Template: template.Template{
+				Content: "ProxyPassword {{.proxy_password}}",
+			},
+		},
+	}
+
+	//test
+	actual, err := fileTemplate.WriteTemplate(map[string]interface{}{
+		"example":        "1",
+		"proxy_password": "hunter2",
+		"config_dir":     parentPath,
+		"pem_dir":        parentPath,
+	}, []string{"config_dir", "pem_dir", "template"}, []string{"proxy_password"}, false)
+	require.NoError(t, err, "should not raise an error writing template")
+	content, err := ioutil.ReadFile(actual["filepath"].(string))
+
+	//assert
+	require.NoError(t, err)
+	require.Contains(t, string(content), "# proxy_password = ********", "should redact secret answers in the prefix")
+	require.Contains(t, string(content), "ProxyPassword hunter2", "should inject secret answers in the content")
+}
@@ -60,7 +60,7 @@ func (t *FileTemplate) WriteTemplate(answerData map[string]interface{}, dryRun b
 	t.data["filepath"] = templatedFilePath
 	answerData["template"] = t.data
 
-	templatedContent, err := utils.PopulateTemplate(t.Content, answerData)
+	templatedContent, err := t.Populate(answerData)
 	if err != nil {
 		return nil, err
 	}
@@ -24,7 +24,7 @@ func (t *PacTemplate) WriteTemplate(answerDataList []map[string]interface{}, dry
 
 	t.data["filepath"] = pacFilePath
 
-	templatedContent, err := utils.PopulateTemplate(t.Content, answerDataList)
+	templatedContent, err := t.Render(answerDataList)
 	if err != nil {
 		return nil, err
 	}
@@ -47,3 +47,8 @@ func (t *PacTemplate) WriteTemplate(answerDataList []map[string]interface{}, dry
 
 	return t.data, nil
 }
+
+// Render populates the PAC template content, without writing it to disk.
+func (t *PacTemplate) Render(answerDataList []map[string]interface{}) (string, error) {
+	return t.Populate(answerDataList)
+}
@@ -1 +1,31 @@
 package template_test
+
+import (
+	"drawbridge/pkg/config/template"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPacTemplate_Render(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	pacTemplate := template.PacTemplate{
+		FileTemplate: template.FileTemplate{
+			FilePath: "~/drawbridge.pac",
+			Template: template.Template{
+				Content: "{{range .}}{{.environment}};{{end}}",
+			},
+		},
+	}
+
+	//test
+	actual, err := pacTemplate.Render([]map[string]interface{}{
+		{"environment": "prod"},
+		{"environment": "test"},
+	})
+
+	//assert
+	require.NoError(t, err, "should not raise an error when rendering pac template")
+	require.Equal(t, "prod;test;", actual, "should populate template with every answer set")
+}
@@ -1,7 +1,19 @@
 package template
 
+import "drawbridge/pkg/utils"
+
+//`Partials` are the shared templates (top-level `partials`), included with `{{template "name" .}}`.
+//`Overrides` are the contents of the config_templates that extend this template, their `{{define}}` blocks replace the
+//blocks with the same name.
 type Template struct {
-	Content string `mapstructure:"content"`
+	Content   string            `mapstructure:"content"`
+	Partials  map[string]string `mapstructure:"-"`
+	Overrides []string          `mapstructure:"-"`
 
 	data map[string]interface{}
 }
+
+// Populate renders the content, with the partials & overrides.
+func (t *Template) Populate(data interface{}) (string, error) {
+	return utils.PopulateTemplateWithPartials(t.Content, t.Partials, t.Overrides, data)
+}
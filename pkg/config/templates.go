@@ -0,0 +1,129 @@
+package config
+
+import (
+	"drawbridge/pkg/config/template"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"strings"
+	gotemplate "text/template"
+	"text/template/parse"
+)
+
+// ResolveConfigTemplates resolves the `extends` chain of every config template, and makes the partials available to
+// their content. A config template inherits every unset field from the template it extends. When its content only
+// contains `{{define}}` blocks they replace the inherited blocks, otherwise the content replaces the inherited content.
+func ResolveConfigTemplates(configTemplates map[string]template.ConfigTemplate, partials map[string]string) (map[string]template.ConfigTemplate, error) {
+	extends := map[string]string{}
+	for templateName, configTemplate := range configTemplates {
+		extends[templateName] = configTemplate.Extends
+	}
+
+	resolvedTemplates := map[string]template.ConfigTemplate{}
+	for templateName := range configTemplates {
+		chain, err := configTemplateExtendsChain(templateName, extends, false)
+		if err != nil {
+			return nil, err
+		}
+
+		// apply the chain from the root template down to this template.
+		resolved := template.ConfigTemplate{}
+		for i := len(chain) - 1; i >= 0; i-- {
+			resolved, err = extendConfigTemplate(resolved, configTemplates[chain[i]])
+			if err != nil {
+				return nil, errors.ConfigTemplateExtendsError(fmt.Sprintf("Could not parse the content of config_templates.%v: %v", chain[i], err))
+			}
+		}
+		resolved.Partials = partials
+		resolvedTemplates[templateName] = resolved
+	}
+	return resolvedTemplates, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+// configTemplateExtendsChain returns the template name, followed by the templates it (transitively) extends. Missing
+// templates are ignored when allowMissing is set (eg. when they may be defined in another config file).
+func configTemplateExtendsChain(templateName string, extends map[string]string, allowMissing bool) ([]string, error) {
+	chain := []string{templateName}
+	for parentName := extends[templateName]; len(parentName) > 0; parentName = extends[parentName] {
+		if utils.SliceIncludes(chain, parentName) {
+			return nil, errors.ConfigTemplateExtendsError(fmt.Sprintf("config_templates.%v has a circular `extends` chain: %v -> %v", templateName, strings.Join(chain, " -> "), parentName))
+		}
+		if _, ok := extends[parentName]; !ok {
+			if allowMissing {
+				break
+			}
+			return nil, errors.ConfigTemplateExtendsError(fmt.Sprintf("config_templates.%v extends `%v`, which does not match any config_templates", chain[len(chain)-1], parentName))
+		}
+		chain = append(chain, parentName)
+	}
+	return chain, nil
+}
+
+func extendConfigTemplate(parent template.ConfigTemplate, child template.ConfigTemplate) (template.ConfigTemplate, error) {
+	extended := parent
+	extended.Extends = child.Extends
+	if len(child.FilePath) > 0 {
+		extended.FilePath = child.FilePath
+	}
+	if len(child.PemFilePath) > 0 {
+		extended.PemFilePath = child.PemFilePath
+	}
+	if child.AgentKeyLifetime != nil {
+		extended.AgentKeyLifetime = child.AgentKeyLifetime
+	}
+	if child.AgentKeyConfirm != nil {
+		extended.AgentKeyConfirm = child.AgentKeyConfirm
+	}
+	if child.JumpHosts != nil {
+		extended.JumpHosts = child.JumpHosts
+	}
+
+	if len(strings.TrimSpace(child.Content)) > 0 {
+		definitionsOnly, err := templateDefinitionsOnly(child.Content)
+		if err != nil {
+			return extended, err
+		}
+		if definitionsOnly && len(parent.Content) > 0 {
+			extended.Overrides = append(append([]string{}, parent.Overrides...), child.Content)
+		} else {
+			extended.Content = child.Content
+			extended.Overrides = nil
+		}
+	}
+	return extended, nil
+}
+
+// templateDefinitionsOnly checks if the template content only contains `{{define}}` blocks (and whitespace).
+func templateDefinitionsOnly(tmplContent string) (bool, error) {
+	tmpl, err := gotemplate.New("content").Funcs(utils.TemplateFuncMap()).Parse(tmplContent)
+	if err != nil {
+		return false, err
+	}
+	return tmpl.Tree == nil || parse.IsEmptyTree(tmpl.Tree.Root), nil
+}
+
+// configTemplateExtendsIssues finds circular `extends` chains in a single config file. Templates that extend templates
+// defined in another config file are checked by ValidateLayers.
+func configTemplateExtendsIssues(configFilePath string, configContent map[string]interface{}) []ValidationIssue {
+	configTemplates, _ := configContent["config_templates"].(map[string]interface{})
+	extends := map[string]string{}
+	for templateName, configTemplate := range configTemplates {
+		configTemplateMap, _ := configTemplate.(map[string]interface{})
+		extends[templateName], _ = configTemplateMap["extends"].(string)
+	}
+
+	issues := []ValidationIssue{}
+	for _, templateName := range utils.MapKeys(configTemplates) {
+		if _, err := configTemplateExtendsChain(templateName, extends, true); err != nil {
+			issues = append(issues, ValidationIssue{
+				FilePath: configFilePath,
+				Key:      fmt.Sprintf("config_templates.%v.extends", templateName),
+				Message:  err.Error(),
+			})
+		}
+	}
+	return issues
+}
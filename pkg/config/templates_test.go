@@ -0,0 +1,108 @@
+package config_test
+
+import (
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/config/template"
+	"drawbridge/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestResolveConfigTemplates_Extends(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	lifetime := 900
+	configTemplates := map[string]template.ConfigTemplate{
+		"base": {
+			PemFilePath: "{{.environment}}.pem",
+			FileTemplate: template.FileTemplate{
+				FilePath: "{{.environment}}",
+				Template: template.Template{Content: `{{template "globals" .}}{{block "bastion" .}}Host bastion{{end}}`},
+			},
+		},
+		"prod": {
+			Extends:          "base",
+			AgentKeyLifetime: &lifetime,
+			FileTemplate: template.FileTemplate{
+				FilePath: "prod-{{.environment}}",
+				Template: template.Template{Content: `{{define "bastion"}}Host bastion.{{.environment}}{{end}}`},
+			},
+		},
+	}
+	partials := map[string]string{"globals": "ForwardAgent yes\n"}
+
+	//test
+	resolved, err := config.ResolveConfigTemplates(configTemplates, partials)
+	require.NoError(t, err)
+	prodTemplate := resolved["prod"]
+	content, err := prodTemplate.Populate(map[string]interface{}{"environment": "prod"})
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "prod-{{.environment}}", prodTemplate.FilePath, "should override the inherited filepath")
+	require.Equal(t, "{{.environment}}.pem", prodTemplate.PemFilePath, "should inherit unset fields")
+	require.Equal(t, &lifetime, prodTemplate.AgentKeyLifetime, "should keep its own fields")
+	require.Equal(t, "ForwardAgent yes\nHost bastion.prod", content, "should include partials, and override inherited blocks")
+}
+
+func TestResolveConfigTemplates_ExtendsReplaceContent(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configTemplates := map[string]template.ConfigTemplate{
+		"base": {
+			FileTemplate: template.FileTemplate{
+				Template: template.Template{Content: `{{block "bastion" .}}Host bastion{{end}}`},
+			},
+		},
+		"custom": {
+			Extends: "base",
+			FileTemplate: template.FileTemplate{
+				Template: template.Template{Content: `Host custom`},
+			},
+		},
+	}
+
+	//test
+	resolved, err := config.ResolveConfigTemplates(configTemplates, nil)
+	require.NoError(t, err)
+	customTemplate := resolved["custom"]
+	content, err := customTemplate.Populate(map[string]interface{}{})
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "Host custom", content, "should replace the inherited content")
+}
+
+func TestResolveConfigTemplates_CircularExtends(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configTemplates := map[string]template.ConfigTemplate{
+		"first":  {Extends: "second"},
+		"second": {Extends: "third"},
+		"third":  {Extends: "first"},
+	}
+
+	//test
+	_, err := config.ResolveConfigTemplates(configTemplates, nil)
+
+	//assert
+	require.IsType(t, errors.ConfigTemplateExtendsError(""), err, "should raise an error for circular extends chains")
+}
+
+func TestResolveConfigTemplates_MissingExtends(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configTemplates := map[string]template.ConfigTemplate{
+		"prod": {Extends: "missing"},
+	}
+
+	//test
+	_, err := config.ResolveConfigTemplates(configTemplates, nil)
+
+	//assert
+	require.IsType(t, errors.ConfigTemplateExtendsError(""), err, "should raise an error when the extended template is missing")
+}
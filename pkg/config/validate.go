@@ -0,0 +1,379 @@
+package config
+
+import (
+	"drawbridge/pkg/config/template"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultsLocation is reported as the FilePath of issues in keys that were not set by any config file.
+const DefaultsLocation = "(defaults)"
+
+// ValidationIssue is a single problem found while validating the config, located by file & (dotted) key.
+type ValidationIssue struct {
+	FilePath string
+	Key      string
+	Message  string
+}
+
+func (i ValidationIssue) String() string {
+	if len(i.Key) == 0 {
+		return fmt.Sprintf("%v: %v", i.FilePath, i.Message)
+	}
+	return fmt.Sprintf("%v: %v: %v", i.FilePath, i.Key, i.Message)
+}
+
+// ValidateLayers validates every config file layer against the config schema, then checks the merged config:
+// - `options.ui_group_priority` and `options.ui_question_hidden` must reference questions
+// - `options.active_config_template` and `options.active_custom_templates` must reference defined templates
+// - question conditions must not have circular dependencies
+// - secret questions must be strings
+// - variables must be resolvable
+// - config template `extends` chains must be resolvable
+// - every template (and the partials it includes) must render, using a synthetic answer set
+func ValidateLayers(configFileLayers []string) ([]ValidationIssue, error) {
+	issues := []ValidationIssue{}
+	for _, configFilePath := range configFileLayers {
+		fileIssues, err := configFileIssues(configFilePath)
+		if err != nil {
+			issues = append(issues, ValidationIssue{FilePath: configFilePath, Message: err.Error()})
+			continue
+		}
+		issues = append(issues, fileIssues...)
+	}
+	if len(issues) > 0 {
+		// the layers cannot be merged.
+		return issues, nil
+	}
+
+	config, err := Load(configFileLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	// the cached remote config is merged beneath all the other layers.
+	if remoteConfigFilePath := remoteConfigLayer(config); len(remoteConfigFilePath) > 0 {
+		configFileLayers = append([]string{remoteConfigFilePath}, configFileLayers...)
+	}
+
+	validator := layersValidator{config: config, configFileLayers: configFileLayers}
+	return validator.issues()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+// templateFilePathIssues ensures that config_templates filepaths are relative (they're created in the options.config_dir)
+// and that custom_templates filepaths are absolute or start with `~/`.
+func templateFilePathIssues(configFilePath string, configContent map[string]interface{}) []ValidationIssue {
+	issues := []ValidationIssue{}
+
+	configTemplates, _ := configContent["config_templates"].(map[string]interface{})
+	for _, templateName := range utils.MapKeys(configTemplates) {
+		configTemplate, _ := configTemplates[templateName].(map[string]interface{})
+		templateFilePath, _ := configTemplate["filepath"].(string)
+		if path.IsAbs(templateFilePath) || strings.HasPrefix(templateFilePath, "~") {
+			issues = append(issues, ValidationIssue{
+				FilePath: configFilePath,
+				Key:      fmt.Sprintf("config_templates.%v.filepath", templateName),
+				Message:  fmt.Sprintf("must be relative, config files are created in the options.config_dir (%v)", templateFilePath),
+			})
+		}
+	}
+
+	customTemplates, _ := configContent["custom_templates"].(map[string]interface{})
+	for _, templateName := range utils.MapKeys(customTemplates) {
+		customTemplate, _ := customTemplates[templateName].(map[string]interface{})
+		templateFilePath, _ := customTemplate["filepath"].(string)
+		// templated paths are checked after rendering.
+		if !strings.HasPrefix(templateFilePath, "{{") && !customTemplateFilePathValid(templateFilePath) {
+			issues = append(issues, ValidationIssue{
+				FilePath: configFilePath,
+				Key:      fmt.Sprintf("custom_templates.%v.filepath", templateName),
+				Message:  fmt.Sprintf("must be absolute or start with `~/` (%v)", templateFilePath),
+			})
+		}
+	}
+	return issues
+}
+
+func customTemplateFilePathValid(templateFilePath string) bool {
+	return path.IsAbs(templateFilePath) || strings.HasPrefix(templateFilePath, "~/")
+}
+
+type layersValidator struct {
+	config           Interface
+	configFileLayers []string
+	issueList        []ValidationIssue
+}
+
+func (v *layersValidator) issues() ([]ValidationIssue, error) {
+	v.issueList = []ValidationIssue{}
+
+	questions, err := v.config.GetQuestions()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := OrderQuestions(questions); err != nil {
+		v.addIssue("questions", err.Error())
+	}
+	for _, questionKey := range SecretQuestionKeys(questions) {
+		question := questions[questionKey]
+		if question.GetType() != "string" {
+			v.addIssue(fmt.Sprintf("questions.%v.secret", questionKey), fmt.Sprintf("secret questions must be of type `string`, not `%v`", question.GetType()))
+		}
+	}
+	for _, optionKey := range []string{"ui_group_priority", "ui_question_hidden"} {
+		for _, questionKey := range v.config.GetStringSlice("options." + optionKey) {
+			if _, ok := questions[questionKey]; !ok {
+				v.addIssue("options."+optionKey, fmt.Sprintf("`%v` does not match any question", questionKey))
+			}
+		}
+	}
+
+	configTemplates, err := v.config.GetConfigTemplates()
+	if _, ok := err.(errors.ConfigTemplateExtendsError); ok {
+		// the config templates cannot be rendered.
+		v.addIssue("config_templates", err.Error())
+		return v.issueList, nil
+	} else if err != nil {
+		return nil, err
+	}
+	activeConfigTemplate := v.config.GetString("options.active_config_template")
+	if _, ok := configTemplates[activeConfigTemplate]; !ok {
+		v.addIssue("options.active_config_template", fmt.Sprintf("`%v` does not match any config_templates", activeConfigTemplate))
+	}
+
+	customTemplates, err := v.config.GetCustomTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, activeCustomTemplate := range v.config.GetStringSlice("options.active_custom_templates") {
+		if _, ok := customTemplates[activeCustomTemplate]; !ok {
+			v.addIssue("options.active_custom_templates", fmt.Sprintf("`%v` does not match any custom_templates", activeCustomTemplate))
+		}
+	}
+
+	answerData, err := v.syntheticAnswerData(questions)
+	if err != nil {
+		return nil, err
+	}
+	err = v.renderTemplates(answerData, configTemplates, customTemplates)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.issueList, nil
+}
+
+// syntheticAnswerData answers every question with its default value, first enum value, or an example value of the
+// correct type.
+func (v *layersValidator) syntheticAnswerData(questions map[string]Question) (map[string]interface{}, error) {
+	answerData := map[string]interface{}{}
+	err := v.config.UnmarshalKey("options", &answerData)
+	if err != nil {
+		return nil, err
+	}
+
+	for questionKey, question := range questions {
+		answerData[questionKey] = syntheticAnswer(question)
+	}
+
+	vars, err := v.config.GetVariables(answerData)
+	if err != nil {
+		v.addIssue("variables", err.Error())
+		vars = map[string]interface{}{}
+	}
+	answerData["vars"] = vars
+	return answerData, nil
+}
+
+func syntheticAnswer(question Question) interface{} {
+	if question.DefaultValue != nil {
+		return question.DefaultValue
+	}
+
+	switch enum := question.Schema["enum"].(type) {
+	case []interface{}:
+		if len(enum) > 0 {
+			return enum[0]
+		}
+	case []string:
+		if len(enum) > 0 {
+			return enum[0]
+		}
+	}
+
+	questionType, _ := question.Schema["type"].(string)
+	switch questionType {
+	case "array":
+		items, _ := utils.StringifyYAMLMapKeys(question.Schema["items"]).(map[string]interface{})
+		return []interface{}{syntheticAnswer(Question{Schema: items})}
+	case "object":
+		return map[string]interface{}{}
+	case "integer":
+		return 1
+	case "number":
+		return 1.0
+	case "boolean":
+		return true
+	default:
+		return "example"
+	}
+}
+
+// renderTemplates dry-renders the filepaths and content of every template, the same way `create` and `proxy` would.
+// Nothing is written to disk.
+func (v *layersValidator) renderTemplates(answerData map[string]interface{}, configTemplates map[string]template.ConfigTemplate, customTemplates map[string]template.FileTemplate) error {
+	configDir, _ := answerData["config_dir"].(string)
+	pemDir, _ := answerData["pem_dir"].(string)
+	activeConfigTemplate := v.config.GetString("options.active_config_template")
+
+	configTemplateNames := []string{}
+	for templateName := range configTemplates {
+		configTemplateNames = append(configTemplateNames, templateName)
+	}
+	sort.Strings(configTemplateNames)
+
+	// used by custom & pac templates as `.config`
+	configData := map[string]interface{}{}
+	for _, templateName := range configTemplateNames {
+		configTemplate := configTemplates[templateName]
+		key := fmt.Sprintf("config_templates.%v", templateName)
+		data := copyAnswerData(answerData)
+
+		templateData := map[string]interface{}{}
+		templateData["pem_filepath"], _ = v.render(key+".pem_filepath", path.Join(pemDir, configTemplate.PemFilePath), data)
+
+		jumpHosts := []string{}
+		for _, jumpHost := range configTemplate.JumpHosts {
+			renderedJumpHost, _ := v.render(key+".jump_hosts", jumpHost, data)
+			jumpHosts = append(jumpHosts, renderedJumpHost)
+		}
+		templateData["jump_hosts"] = jumpHosts
+		templateData["proxy_jump"] = strings.Join(jumpHosts, ",")
+
+		configFilePath, _ := v.render(key+".filepath", path.Join(configDir, configTemplate.FilePath), data)
+		templateData["filepath"] = configFilePath
+		templateData["known_hosts_filepath"] = template.KnownHostsFilePath(configFilePath)
+
+		data["template"] = templateData
+		v.renderContent(key+".content", configTemplate.Template, data)
+
+		// fallback to the first template, when the active template is missing (already reported).
+		if templateName == activeConfigTemplate || len(configData) == 0 {
+			configData = templateData
+		}
+	}
+
+	customTemplateNames := []string{}
+	for templateName := range customTemplates {
+		customTemplateNames = append(customTemplateNames, templateName)
+	}
+	sort.Strings(customTemplateNames)
+
+	for _, templateName := range customTemplateNames {
+		customTemplate := customTemplates[templateName]
+		key := fmt.Sprintf("custom_templates.%v", templateName)
+		data := copyAnswerData(answerData)
+		data["config"] = configData
+		data["custom"] = []interface{}{}
+
+		customFilePath, ok := v.render(key+".filepath", customTemplate.FilePath, data)
+		if ok && !customTemplateFilePathValid(customFilePath) {
+			v.addIssue(key+".filepath", fmt.Sprintf("must be absolute or start with `~/` (rendered as %v)", customFilePath))
+		}
+
+		data["template"] = map[string]interface{}{"filepath": customFilePath}
+		v.renderContent(key+".content", customTemplate.Template, data)
+	}
+
+	pacTemplate, err := v.config.GetPacTemplate()
+	if err != nil {
+		return err
+	}
+	pacData := copyAnswerData(answerData)
+	pacData["config"] = configData
+	v.renderContent("pac_template.content", pacTemplate.Template, []map[string]interface{}{pacData})
+	return nil
+}
+
+func copyAnswerData(answerData map[string]interface{}) map[string]interface{} {
+	data := map[string]interface{}{}
+	for k, v := range answerData {
+		data[k] = v
+	}
+	return data
+}
+
+func (v *layersValidator) render(key string, tmplContent string, data interface{}) (string, bool) {
+	rendered, err := utils.PopulateTemplate(tmplContent, data)
+	if err != nil {
+		v.addIssue(key, fmt.Sprintf("could not be rendered: %v", err))
+		return "", false
+	}
+	return rendered, true
+}
+
+// renderContent renders the template content, with its partials & overrides.
+func (v *layersValidator) renderContent(key string, tmpl template.Template, data interface{}) {
+	if _, err := tmpl.Populate(data); err != nil {
+		v.addIssue(key, fmt.Sprintf("could not be rendered: %v", err))
+	}
+}
+
+func (v *layersValidator) addIssue(key string, message string) {
+	v.issueList = append(v.issueList, ValidationIssue{FilePath: v.keyLocation(key), Key: key, Message: message})
+}
+
+// keyLocation returns the highest precedence config file that sets the (dotted) key.
+func (v *layersValidator) keyLocation(key string) string {
+	for i := len(v.configFileLayers) - 1; i >= 0; i-- {
+		configFilePath, err := utils.ExpandPath(v.configFileLayers[i])
+		if err != nil {
+			continue
+		}
+		configFileContent, err := ioutil.ReadFile(configFilePath)
+		if err != nil {
+			continue
+		}
+		configContent := map[string]interface{}{}
+		if yaml.Unmarshal(configFileContent, &configContent) != nil {
+			continue
+		}
+
+		var node interface{} = configContent
+		found := true
+		for _, keyPart := range strings.Split(key, ".") {
+			nodeMap, ok := utils.StringifyYAMLMapKeys(node).(map[string]interface{})
+			if !ok {
+				found = false
+				break
+			}
+			node, found = lookupKey(nodeMap, keyPart)
+			if !found {
+				break
+			}
+		}
+		if found {
+			return configFilePath
+		}
+	}
+	return DefaultsLocation
+}
+
+// viper keys are case-insensitive.
+func lookupKey(nodeMap map[string]interface{}, key string) (interface{}, bool) {
+	for k, v := range nodeMap {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,103 @@
+package config_test
+
+import (
+	"drawbridge/pkg/config"
+	"github.com/stretchr/testify/require"
+	"path"
+	"testing"
+)
+
+func TestValidateLayers(t *testing.T) {
+	t.Parallel()
+
+	//test
+	issues, err := config.ValidateLayers([]string{path.Join("testdata", "valid_template_references.yaml")})
+
+	//assert
+	require.NoError(t, err)
+	require.Empty(t, issues, "should not find any issues in a valid config")
+}
+
+func TestValidateLayers_InvalidTemplateFilePaths(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configFilePath := path.Join("testdata", "invalid_template_filepaths.yaml")
+
+	//test
+	issues, err := config.ValidateLayers([]string{configFilePath})
+
+	//assert
+	require.NoError(t, err)
+	require.Len(t, issues, 2, "should find an issue for each invalid filepath")
+	require.Equal(t, "config_templates.absolute.filepath", issues[0].Key, "config template filepaths must be relative")
+	require.Equal(t, "custom_templates.relative.filepath", issues[1].Key, "custom template filepaths must be absolute")
+	require.Contains(t, issues[0].FilePath, configFilePath, "should report the file containing the issue")
+}
+
+func TestValidateLayers_InvalidReferences(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configFilePath := path.Join("testdata", "invalid_references.yaml")
+
+	//test
+	issues, err := config.ValidateLayers([]string{configFilePath})
+
+	//assert
+	require.NoError(t, err)
+	issueKeys := []string{}
+	for _, issue := range issues {
+		issueKeys = append(issueKeys, issue.Key)
+		require.Contains(t, issue.FilePath, configFilePath, "should report the file containing the issue")
+	}
+	require.Equal(t, []string{
+		"options.ui_group_priority",
+		"options.active_config_template",
+		"config_templates.broken.content",
+	}, issueKeys, "should find broken references, and templates that cannot be rendered")
+}
+
+func TestValidateLayers_InvalidSecretQuestions(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configFilePath := path.Join("testdata", "invalid_secret_questions.yaml")
+
+	//test
+	issues, err := config.ValidateLayers([]string{configFilePath})
+
+	//assert
+	require.NoError(t, err)
+	require.Len(t, issues, 1, "should only find an issue for the non-string secret question")
+	require.Equal(t, "questions.vault_pin.secret", issues[0].Key, "secret questions must be strings")
+}
+
+func TestValidateLayers_Partials(t *testing.T) {
+	t.Parallel()
+
+	//test
+	issues, err := config.ValidateLayers([]string{path.Join("testdata", "valid_partials.yaml")})
+
+	//assert
+	require.NoError(t, err)
+	require.Empty(t, issues, "should render templates that include partials & extend other templates")
+}
+
+func TestValidateLayers_CircularExtends(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	configFilePath := path.Join("testdata", "invalid_extends_cycle.yaml")
+
+	//test
+	issues, err := config.ValidateLayers([]string{configFilePath})
+
+	//assert
+	require.NoError(t, err)
+	issueKeys := []string{}
+	for _, issue := range issues {
+		issueKeys = append(issueKeys, issue.Key)
+	}
+	require.Equal(t, []string{"config_templates.base.extends", "config_templates.prod.extends"}, issueKeys, "should find circular extends chains, missing templates may be defined in other layers")
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// ResolveVariables populates every variable template using the answer data. Variables are available to each other (and
+// to config, custom & PAC templates) as `.vars.<name>`, and are resolved in dependency order.
+func ResolveVariables(variables map[string]string, answerData map[string]interface{}) (map[string]interface{}, error) {
+	resolvedVars := map[string]interface{}{}
+	resolving := map[string]bool{}
+
+	var resolve func(name string, chain []string) error
+	resolve = func(name string, chain []string) error {
+		if _, ok := resolvedVars[name]; ok {
+			return nil
+		}
+		chain = append(chain, name)
+		if resolving[name] {
+			return errors.VariableResolutionError(fmt.Sprintf("Circular reference between variables: %v", strings.Join(chain, " -> ")))
+		}
+
+		varTemplate, ok := variables[name]
+		if !ok {
+			return errors.VariableResolutionError(fmt.Sprintf("Variable `%v` is not defined (referenced by %v)", name, strings.Join(chain[:len(chain)-1], " -> ")))
+		}
+
+		references, err := variableReferences(varTemplate)
+		if err != nil {
+			return errors.VariableResolutionError(fmt.Sprintf("Variable `%v` is not a valid template: %v", name, err))
+		}
+
+		resolving[name] = true
+		for _, reference := range references {
+			if err := resolve(reference, chain); err != nil {
+				return err
+			}
+		}
+		resolving[name] = false
+
+		templateData := map[string]interface{}{}
+		for k, v := range answerData {
+			templateData[k] = v
+		}
+		templateData["vars"] = resolvedVars
+
+		value, err := utils.PopulateTemplate(varTemplate, templateData)
+		if err != nil {
+			return errors.VariableResolutionError(fmt.Sprintf("Could not populate variable `%v`: %v", name, err))
+		}
+		resolvedVars[name] = value
+		return nil
+	}
+
+	// sorted, so that errors are deterministic.
+	names := []string{}
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := resolve(name, []string{}); err != nil {
+			return nil, err
+		}
+	}
+	return resolvedVars, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+// variableReferences returns the (sorted) names of all variables referenced as `.vars.<name>` in the template.
+func variableReferences(varTemplate string) ([]string, error) {
+	fields, err := templateFields(varTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	referenceSet := map[string]bool{}
+	for _, field := range fields {
+		if len(field) > 1 && field[0] == "vars" {
+			referenceSet[field[1]] = true
+		}
+	}
+
+	references := []string{}
+	for reference := range referenceSet {
+		references = append(references, reference)
+	}
+	sort.Strings(references)
+	return references, nil
+}
+
+// templateFields returns every field (eg. `.vars.name` as ["vars", "name"]) referenced in the template.
+func templateFields(tmplContent string) ([][]string, error) {
+	tmpl, err := template.New("fields").Funcs(utils.TemplateFuncMap()).Parse(tmplContent)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := [][]string{}
+	walkTemplateFields(tmpl.Tree.Root, &fields)
+	return fields, nil
+}
+
+func walkTemplateFields(node parse.Node, fields *[][]string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkTemplateFields(child, fields)
+		}
+	case *parse.ActionNode:
+		walkTemplateFields(n.Pipe, fields)
+	case *parse.IfNode:
+		walkBranchTemplateFields(&n.BranchNode, fields)
+	case *parse.RangeNode:
+		walkBranchTemplateFields(&n.BranchNode, fields)
+	case *parse.WithNode:
+		walkBranchTemplateFields(&n.BranchNode, fields)
+	case *parse.TemplateNode:
+		walkTemplateFields(n.Pipe, fields)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walkTemplateFields(cmd, fields)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkTemplateFields(arg, fields)
+		}
+	case *parse.ChainNode:
+		walkTemplateFields(n.Node, fields)
+	case *parse.FieldNode:
+		*fields = append(*fields, n.Ident)
+	}
+}
+
+func walkBranchTemplateFields(n *parse.BranchNode, fields *[][]string) {
+	walkTemplateFields(n.Pipe, fields)
+	walkTemplateFields(n.List, fields)
+	walkTemplateFields(n.ElseList, fields)
+}
@@ -0,0 +1,66 @@
+package config_test
+
+import (
+	"drawbridge/pkg/config"
+	"drawbridge/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestResolveVariables(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	variables := map[string]string{
+		"base_domain":     "example.com",
+		"env_domain":      "{{.environment}}.{{.vars.base_domain}}",
+		"bastion_address": "bastion.{{.vars.env_domain}}",
+	}
+	answerData := map[string]interface{}{
+		"environment": "stage",
+	}
+
+	//test
+	vars, err := config.ResolveVariables(variables, answerData)
+
+	//assert
+	require.NoError(t, err, "should resolve variables")
+	require.Equal(t, map[string]interface{}{
+		"base_domain":     "example.com",
+		"env_domain":      "stage.example.com",
+		"bastion_address": "bastion.stage.example.com",
+	}, vars, "should resolve variables referencing answers and other variables")
+}
+
+func TestResolveVariables_CircularReference(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	variables := map[string]string{
+		"first":  "{{if .enabled}}{{.vars.second}}{{end}}",
+		"second": "{{.vars.third}}",
+		"third":  "{{.vars.first}}",
+	}
+
+	//test
+	_, err := config.ResolveVariables(variables, map[string]interface{}{"enabled": true})
+
+	//assert
+	require.IsType(t, errors.VariableResolutionError(""), err, "should detect circular references")
+	require.Contains(t, err.Error(), "first -> second -> third -> first", "should report the reference chain")
+}
+
+func TestResolveVariables_UndefinedVariable(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	variables := map[string]string{
+		"domain": "{{.vars.missing}}",
+	}
+
+	//test
+	_, err := config.ResolveVariables(variables, map[string]interface{}{})
+
+	//assert
+	require.IsType(t, errors.VariableResolutionError(""), err, "should raise an error for undefined variables")
+}
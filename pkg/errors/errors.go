@@ -90,3 +90,108 @@ type InvalidArgumentsError string
 func (str InvalidArgumentsError) Error() string {
 	return fmt.Sprintf("InvalidArgumentsError: %q", string(str))
 }
+
+// Raised when a rendered ssh config uses options that the native ssh client cannot handle
+type SshConfigUnsupportedError string
+
+func (str SshConfigUnsupportedError) Error() string {
+	return fmt.Sprintf("SshConfigUnsupportedError: %q", string(str))
+}
+
+// Raised when the connection to a bastion is lost
+type TunnelDisconnectedError string
+
+func (str TunnelDisconnectedError) Error() string {
+	return fmt.Sprintf("TunnelDisconnectedError: %q", string(str))
+}
+
+// Raised when the tunnel daemon is already running (or is not running when it should be)
+type TunnelDaemonError string
+
+func (str TunnelDaemonError) Error() string {
+	return fmt.Sprintf("TunnelDaemonError: %q", string(str))
+}
+
+// Raised when a remote command fails on one or more hosts
+type RemoteCommandError string
+
+func (str RemoteCommandError) Error() string {
+	return fmt.Sprintf("RemoteCommandError: %q", string(str))
+}
+
+// Raised when the bastion hostname in a rendered ssh config cannot be resolved
+type BastionDnsError string
+
+func (str BastionDnsError) Error() string {
+	return fmt.Sprintf("BastionDnsError: %q", string(str))
+}
+
+// Raised when the bastion host is not accepting connections on its ssh port
+type BastionUnreachableError string
+
+func (str BastionUnreachableError) Error() string {
+	return fmt.Sprintf("BastionUnreachableError: %q", string(str))
+}
+
+// Raised when a host presents a different key than the one recorded in the known_hosts file
+type HostKeyChangedError string
+
+func (str HostKeyChangedError) Error() string {
+	return fmt.Sprintf("HostKeyChangedError: %q", string(str))
+}
+
+// Raised when the remote (shared) config cannot be fetched
+type RemoteConfigError string
+
+func (str RemoteConfigError) Error() string {
+	return fmt.Sprintf("RemoteConfigError: %q", string(str))
+}
+
+// Raised when a config variable cannot be resolved (eg. circular references)
+type VariableResolutionError string
+
+func (str VariableResolutionError) Error() string {
+	return fmt.Sprintf("VariableResolutionError: %q", string(str))
+}
+
+// Raised when `drawbridge init` would overwrite an existing config file
+type ConfigFileExistsError string
+
+func (str ConfigFileExistsError) Error() string {
+	return fmt.Sprintf("ConfigFileExistsError: %q", string(str))
+}
+
+// Raised when the conditions of dependent questions cannot be evaluated (eg. circular dependencies)
+type QuestionDependencyError string
+
+func (str QuestionDependencyError) Error() string {
+	return fmt.Sprintf("QuestionDependencyError: %q", string(str))
+}
+
+// Raised when the `choices_from` list of a question cannot be loaded
+type QuestionChoicesError string
+
+func (str QuestionChoicesError) Error() string {
+	return fmt.Sprintf("QuestionChoicesError: %q", string(str))
+}
+
+// Raised when a secret backend cannot store or load secrets (eg. wrong passphrase, keyring unavailable)
+type SecretBackendError string
+
+func (str SecretBackendError) Error() string {
+	return fmt.Sprintf("SecretBackendError: %q", string(str))
+}
+
+// Raised when a secret answer is missing from the secret backend
+type SecretNotFoundError string
+
+func (str SecretNotFoundError) Error() string {
+	return fmt.Sprintf("SecretNotFoundError: %q", string(str))
+}
+
+// Raised when the `extends` chain of a config template cannot be resolved (eg. circular or missing templates)
+type ConfigTemplateExtendsError string
+
+func (str ConfigTemplateExtendsError) Error() string {
+	return fmt.Sprintf("ConfigTemplateExtendsError: %q", string(str))
+}
@@ -36,4 +36,19 @@ func TestErrors(t *testing.T) {
 	require.Implements(t, (*error)(nil), errors.AnswerFormatError("test"), "should implement the error interface")
 	require.Implements(t, (*error)(nil), errors.DependencyMissingError("test"), "should implement the error interface")
 	require.Implements(t, (*error)(nil), errors.PemKeyMissingError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.SshConfigUnsupportedError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.TunnelDisconnectedError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.TunnelDaemonError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.RemoteCommandError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.BastionDnsError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.BastionUnreachableError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.HostKeyChangedError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.RemoteConfigError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.VariableResolutionError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.ConfigFileExistsError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.QuestionDependencyError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.QuestionChoicesError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.SecretBackendError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.SecretNotFoundError("test"), "should implement the error interface")
+	require.Implements(t, (*error)(nil), errors.ConfigTemplateExtendsError("test"), "should implement the error interface")
 }
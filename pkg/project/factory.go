@@ -68,6 +68,11 @@ func CreateProjectFromConfigDirAnswerFile(configFilePath string) (projectData, e
 	return parseAnswerFile(configFilePath)
 }
 
+// list of all the answers files (one per drawbridge managed config) in the config directory
+func AnswerFilesInConfigDir(configData config.Interface) ([]string, error) {
+	return answerFilesInConfigDir(configData.GetString("options.config_dir"))
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // Helpers
 
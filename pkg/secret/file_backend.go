@@ -0,0 +1,207 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/crypto/scrypt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// file format: magic | scrypt salt | gcm nonce | gcm sealed json map (secret id -> value)
+var fileMagic = []byte("drawbridge-secrets-v1\n")
+
+const (
+	fileSaltSize = 16
+
+	// scrypt parameters recommended for interactive logins.
+	scryptN      = 32768
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// FileBackend stores secrets in a local file, encrypted with AES-GCM using a key derived (scrypt) from a passphrase.
+// The passphrase is only requested once, and must be confirmed (requested with confirm set) when the file is created.
+type FileBackend struct {
+	FilePath   string
+	Passphrase func(confirm bool) (string, error)
+
+	mutex      sync.Mutex
+	passphrase string
+}
+
+func (b *FileBackend) Name() string {
+	return BackendFile
+}
+
+func (b *FileBackend) Get(id string) (string, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	secrets, err := b.read()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[id]
+	if !ok {
+		return "", errors.SecretNotFoundError(fmt.Sprintf("Could not find secret %v in %v", id, b.FilePath))
+	}
+	return value, nil
+}
+
+func (b *FileBackend) Set(id string, value string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	secrets, err := b.read()
+	if err != nil {
+		return err
+	}
+	secrets[id] = value
+	return b.write(secrets)
+}
+
+func (b *FileBackend) Delete(id string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	secrets, err := b.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[id]; !ok {
+		return nil
+	}
+	delete(secrets, id)
+	return b.write(secrets)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+// read decrypts the secrets file. A missing file has no secrets.
+func (b *FileBackend) read() (map[string]string, error) {
+	secretsFilePath, err := utils.ExpandPath(b.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(secretsFilePath)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(content, fileMagic) || len(content) < len(fileMagic)+fileSaltSize {
+		return nil, errors.SecretBackendError(fmt.Sprintf("%v is not a drawbridge secrets file", b.FilePath))
+	}
+	content = content[len(fileMagic):]
+	salt := content[:fileSaltSize]
+
+	gcm, err := b.cipher(salt, false)
+	if err != nil {
+		return nil, err
+	}
+	sealed := content[fileSaltSize:]
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.SecretBackendError(fmt.Sprintf("%v is truncated", b.FilePath))
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		// don't keep a wrong passphrase.
+		b.passphrase = ""
+		return nil, errors.SecretBackendError(fmt.Sprintf("Could not decrypt %v, the passphrase is incorrect or the file is corrupted", b.FilePath))
+	}
+
+	secrets := map[string]string{}
+	err = json.Unmarshal(plaintext, &secrets)
+	if err != nil {
+		return nil, errors.SecretBackendError(fmt.Sprintf("Could not parse %v: %v", b.FilePath, err))
+	}
+	return secrets, nil
+}
+
+// write re-encrypts all the secrets, using a new salt & nonce.
+func (b *FileBackend) write(secrets map[string]string) error {
+	secretsFilePath, err := utils.ExpandPath(b.FilePath)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, fileSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+	// a typo in the passphrase of a new secrets file would lock the user out of their secrets.
+	gcm, err := b.cipher(salt, !utils.FileExists(secretsFilePath))
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	content := append([]byte{}, fileMagic...)
+	content = append(content, salt...)
+	content = append(content, nonce...)
+	content = gcm.Seal(content, nonce, plaintext, nil)
+
+	err = os.MkdirAll(filepath.Dir(secretsFilePath), 0700)
+	if err != nil {
+		return err
+	}
+	return utils.FileWrite(secretsFilePath, string(content), 0600, false)
+}
+
+func (b *FileBackend) cipher(salt []byte, newFile bool) (cipher.AEAD, error) {
+	if len(b.passphrase) == 0 {
+		if b.Passphrase == nil {
+			return nil, errors.SecretBackendError("A passphrase is required to use the secrets file")
+		}
+		passphrase, err := b.Passphrase(false)
+		if err != nil {
+			return nil, err
+		}
+		if len(passphrase) == 0 {
+			return nil, errors.SecretBackendError("A passphrase is required to use the secrets file")
+		}
+		if newFile {
+			confirmation, err := b.Passphrase(true)
+			if err != nil {
+				return nil, err
+			}
+			if confirmation != passphrase {
+				return nil, errors.SecretBackendError("The passphrases do not match")
+			}
+		}
+		b.passphrase = passphrase
+	}
+
+	key, err := scrypt.Key([]byte(b.passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,132 @@
+package secret_test
+
+import (
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/secret"
+	"drawbridge/pkg/utils"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func staticPassphrase(passphrase string) func(bool) (string, error) {
+	return func(confirm bool) (string, error) {
+		return passphrase, nil
+	}
+}
+
+func TestFileBackend_SetGet(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	secretsFilePath := path.Join(parentPath, secret.FileName)
+	backend := secret.FileBackend{FilePath: secretsFilePath, Passphrase: staticPassphrase("correct horse")}
+
+	//test
+	err = backend.Set("prod#proxy_password", "hunter2")
+	require.NoError(t, err)
+	reopened := secret.FileBackend{FilePath: secretsFilePath, Passphrase: staticPassphrase("correct horse")}
+	value, err := reopened.Get("prod#proxy_password")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", value, "should decrypt the stored secret")
+	content, err := ioutil.ReadFile(secretsFilePath)
+	require.NoError(t, err)
+	require.False(t, strings.Contains(string(content), "hunter2"), "should not store the secret in clear text")
+	info, err := os.Stat(secretsFilePath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm(), "should only be readable by the user")
+}
+
+func TestFileBackend_WrongPassphrase(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	secretsFilePath := path.Join(parentPath, secret.FileName)
+	backend := secret.FileBackend{FilePath: secretsFilePath, Passphrase: staticPassphrase("correct horse")}
+	require.NoError(t, backend.Set("prod#proxy_password", "hunter2"))
+
+	//test
+	reopened := secret.FileBackend{FilePath: secretsFilePath, Passphrase: staticPassphrase("battery staple")}
+	_, err = reopened.Get("prod#proxy_password")
+
+	//assert
+	require.IsType(t, errors.SecretBackendError(""), err, "should fail to decrypt with the wrong passphrase")
+}
+
+func TestFileBackend_NewFilePassphraseMismatch(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	secretsFilePath := path.Join(parentPath, secret.FileName)
+	confirmRequested := false
+	backend := secret.FileBackend{FilePath: secretsFilePath, Passphrase: func(confirm bool) (string, error) {
+		if confirm {
+			confirmRequested = true
+			return "correct hrose", nil
+		}
+		return "correct horse", nil
+	}}
+
+	//test
+	err = backend.Set("prod#proxy_password", "hunter2")
+
+	//assert
+	require.True(t, confirmRequested, "should confirm the passphrase of a new secrets file")
+	require.IsType(t, errors.SecretBackendError(""), err, "should fail when the confirmation does not match")
+	require.False(t, utils.FileExists(secretsFilePath), "should not create the secrets file")
+}
+
+func TestFileBackend_ExistingFileNoConfirmation(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	secretsFilePath := path.Join(parentPath, secret.FileName)
+	backend := secret.FileBackend{FilePath: secretsFilePath, Passphrase: staticPassphrase("correct horse")}
+	require.NoError(t, backend.Set("prod#proxy_password", "hunter2"))
+
+	//test
+	reopened := secret.FileBackend{FilePath: secretsFilePath, Passphrase: func(confirm bool) (string, error) {
+		require.False(t, confirm, "should not confirm the passphrase of an existing secrets file")
+		return "correct horse", nil
+	}}
+	err = reopened.Set("prod#vault_token", "s.token")
+
+	//assert
+	require.NoError(t, err)
+}
+
+func TestFileBackend_Delete(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	backend := secret.FileBackend{FilePath: path.Join(parentPath, secret.FileName), Passphrase: staticPassphrase("correct horse")}
+	require.NoError(t, backend.Set("prod#proxy_password", "hunter2"))
+
+	//test
+	err = backend.Delete("prod#proxy_password")
+	require.NoError(t, err)
+	_, err = backend.Get("prod#proxy_password")
+
+	//assert
+	require.IsType(t, errors.SecretNotFoundError(""), err, "should remove the secret")
+}
@@ -0,0 +1,110 @@
+package secret
+
+import (
+	"drawbridge/pkg/errors"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// KeyringBackend stores secrets in the OS keyring, using the `security` binary on macOS (login keychain) and the
+// `secret-tool` binary on linux (Secret Service, eg. gnome-keyring or KWallet).
+type KeyringBackend struct {
+	Service string
+}
+
+func (b *KeyringBackend) Name() string {
+	return BackendKeyring
+}
+
+// Available checks that the keyring binary for this OS is installed.
+func (b *KeyringBackend) Available() bool {
+	keyringBin := b.keyringBin()
+	if len(keyringBin) == 0 {
+		return false
+	}
+	_, err := exec.LookPath(keyringBin)
+	return err == nil
+}
+
+func (b *KeyringBackend) Get(id string) (string, error) {
+	var keyringCmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		keyringCmd = exec.Command("security", "find-generic-password", "-s", b.Service, "-a", id, "-w")
+	default:
+		keyringCmd = exec.Command("secret-tool", "lookup", "service", b.Service, "account", id)
+	}
+
+	output, err := keyringCmd.Output()
+	if _, exited := err.(*exec.ExitError); err != nil && !exited {
+		return "", b.commandError("load", id, err)
+	}
+	// missing secrets are reported with a non-zero exit code (security) or an empty output (secret-tool).
+	if err != nil || len(output) == 0 {
+		return "", errors.SecretNotFoundError(fmt.Sprintf("Could not find secret %v in the OS keyring", id))
+	}
+	return strings.TrimSuffix(string(output), "\n"), nil
+}
+
+func (b *KeyringBackend) Set(id string, value string) error {
+	var keyringCmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// the secret must never be passed as an argument (it would be visible to other users in `ps`), so the command
+		// is read from stdin (`security -i`), with the secret hex encoded (-X). -U updates the existing secret.
+		keyringCmd = exec.Command("security", "-i")
+		keyringCmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %v -a %v -X %v\n",
+			securityQuote(b.Service), securityQuote(id), hex.EncodeToString([]byte(value))))
+	default:
+		// the secret is read from stdin.
+		keyringCmd = exec.Command("secret-tool", "store", "--label", fmt.Sprintf("%v: %v", b.Service, id), "service", b.Service, "account", id)
+		keyringCmd.Stdin = strings.NewReader(value)
+	}
+
+	output, err := keyringCmd.CombinedOutput()
+	// `security -i` reports command errors in its output, not in its exit code.
+	if err != nil || strings.Contains(string(output), "security:") {
+		return b.commandError("store", id, fmt.Errorf("%v", strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+func (b *KeyringBackend) Delete(id string) error {
+	var keyringCmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		keyringCmd = exec.Command("security", "delete-generic-password", "-s", b.Service, "-a", id)
+	default:
+		keyringCmd = exec.Command("secret-tool", "clear", "service", b.Service, "account", id)
+	}
+
+	// deleting a missing secret is not an error.
+	keyringCmd.Run()
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+func (b *KeyringBackend) keyringBin() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "security"
+	case "linux", "freebsd", "openbsd":
+		return "secret-tool"
+	default:
+		return ""
+	}
+}
+
+// securityQuote quotes an argument for the `security -i` command parser.
+func securityQuote(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+func (b *KeyringBackend) commandError(action string, id string, err error) error {
+	return errors.SecretBackendError(fmt.Sprintf("Could not %v secret %v in the OS keyring (%v): %v", action, id, b.keyringBin(), err))
+}
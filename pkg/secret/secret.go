@@ -0,0 +1,114 @@
+package secret
+
+import (
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"os"
+	"path"
+)
+
+const (
+	BackendAuto    = "auto"
+	BackendKeyring = "keyring"
+	BackendFile    = "file"
+
+	// FileName is the encrypted secrets file used by the file backend, stored in the config_dir.
+	FileName = ".secrets.enc"
+
+	// PassphraseEnv can be used to provide the file backend passphrase non-interactively.
+	PassphraseEnv = "DRAWBRIDGE_SECRETS_PASSPHRASE"
+
+	// Redacted replaces secret answers in answers files, config file headers & the CLI output.
+	Redacted = "********"
+
+	keyringService = "drawbridge"
+)
+
+// Backend stores secret answers outside of the answers files. Secrets are identified by the rendered config filepath
+// and the question key (see Id)
+type Backend interface {
+	Name() string
+	Get(id string) (string, error)
+	Set(id string, value string) error
+	Delete(id string) error
+}
+
+// NewBackend returns the requested secret backend. `auto` uses the OS keyring when it's available, and falls back to the
+// encrypted file (in the configDir).
+func NewBackend(backendType string, configDir string) (Backend, error) {
+	fileBackend := &FileBackend{
+		FilePath:   path.Join(configDir, FileName),
+		Passphrase: StdinPassphrase,
+	}
+	keyringBackend := &KeyringBackend{Service: keyringService}
+
+	switch backendType {
+	case BackendFile:
+		return fileBackend, nil
+	case BackendKeyring:
+		if !keyringBackend.Available() {
+			return nil, errors.SecretBackendError("The OS keyring is not available (requires `security` on macOS or `secret-tool` on linux)")
+		}
+		return keyringBackend, nil
+	case BackendAuto, "":
+		if keyringBackend.Available() {
+			return keyringBackend, nil
+		}
+		return fileBackend, nil
+	default:
+		return nil, errors.SecretBackendError(fmt.Sprintf("Unsupported secret backend: %v", backendType))
+	}
+}
+
+// Id identifies the secret answer for a question, in a rendered drawbridge config.
+func Id(configFilePath string, questionKey string) string {
+	return fmt.Sprintf("%v#%v", configFilePath, questionKey)
+}
+
+// Redact returns a copy of the answerData, with the secret answers replaced.
+func Redact(answerData map[string]interface{}, secretKeys []string) map[string]interface{} {
+	redacted := map[string]interface{}{}
+	for k, v := range answerData {
+		if v != nil && utils.SliceIncludes(secretKeys, k) {
+			v = Redacted
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// Inject returns a copy of the (redacted) answerData, with the secret answers loaded from the backend. Secrets are only
+// loaded at render time, they're never persisted with the answers.
+func Inject(backend Backend, answerData map[string]interface{}, secretKeys []string) (map[string]interface{}, error) {
+	injected := map[string]interface{}{}
+	for k, v := range answerData {
+		injected[k] = v
+	}
+
+	configData, _ := answerData["config"].(map[string]interface{})
+	configFilePath, _ := configData["filepath"].(string)
+	for _, secretKey := range secretKeys {
+		if answerData[secretKey] != Redacted {
+			continue
+		}
+		value, err := backend.Get(Id(configFilePath, secretKey))
+		if err != nil {
+			return nil, err
+		}
+		injected[secretKey] = value
+	}
+	return injected, nil
+}
+
+// StdinPassphrase reads the file backend passphrase from the DRAWBRIDGE_SECRETS_PASSPHRASE environment variable, or
+// asks the user for it (again, when confirming the passphrase of a new secrets file).
+func StdinPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(PassphraseEnv); len(passphrase) > 0 {
+		return passphrase, nil
+	}
+	if confirm {
+		return utils.StdinQueryPassword("Please confirm the passphrase for your new drawbridge secrets file:")
+	}
+	return utils.StdinQueryPassword("Please enter the passphrase for your drawbridge secrets file:")
+}
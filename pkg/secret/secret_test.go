@@ -0,0 +1,103 @@
+package secret_test
+
+import (
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/secret"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type memoryBackend map[string]string
+
+func (b memoryBackend) Name() string { return "memory" }
+
+func (b memoryBackend) Get(id string) (string, error) {
+	value, ok := b[id]
+	if !ok {
+		return "", errors.SecretNotFoundError(id)
+	}
+	return value, nil
+}
+
+func (b memoryBackend) Set(id string, value string) error {
+	b[id] = value
+	return nil
+}
+
+func (b memoryBackend) Delete(id string) error {
+	delete(b, id)
+	return nil
+}
+
+func TestNewBackend_File(t *testing.T) {
+	t.Parallel()
+
+	//test
+	backend, err := secret.NewBackend(secret.BackendFile, "/tmp/drawbridge")
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, secret.BackendFile, backend.Name(), "should return the file backend")
+	require.Equal(t, "/tmp/drawbridge/.secrets.enc", backend.(*secret.FileBackend).FilePath, "should store the secrets file in the config_dir")
+}
+
+func TestNewBackend_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	//test
+	_, err := secret.NewBackend("vault", "/tmp/drawbridge")
+
+	//assert
+	require.IsType(t, errors.SecretBackendError(""), err, "should raise an error for unsupported backends")
+}
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	answerData := map[string]interface{}{"environment": "prod", "proxy_password": "hunter2", "vault_token": nil}
+
+	//test
+	redacted := secret.Redact(answerData, []string{"proxy_password", "vault_token"})
+
+	//assert
+	require.Equal(t, map[string]interface{}{"environment": "prod", "proxy_password": secret.Redacted, "vault_token": nil}, redacted, "should redact non-empty secret answers")
+	require.Equal(t, "hunter2", answerData["proxy_password"], "should not modify the original answers")
+}
+
+func TestInject(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	backend := memoryBackend{}
+	backend.Set(secret.Id("/tmp/drawbridge/prod", "proxy_password"), "hunter2")
+	answerData := map[string]interface{}{
+		"environment":    "prod",
+		"proxy_password": secret.Redacted,
+		"config":         map[string]interface{}{"filepath": "/tmp/drawbridge/prod"},
+	}
+
+	//test
+	injected, err := secret.Inject(backend, answerData, []string{"proxy_password"})
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", injected["proxy_password"], "should load the secret answer from the backend")
+	require.Equal(t, secret.Redacted, answerData["proxy_password"], "should not modify the original answers")
+}
+
+func TestInject_Missing(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	answerData := map[string]interface{}{
+		"proxy_password": secret.Redacted,
+		"config":         map[string]interface{}{"filepath": "/tmp/drawbridge/prod"},
+	}
+
+	//test
+	_, err := secret.Inject(memoryBackend{}, answerData, []string{"proxy_password"})
+
+	//assert
+	require.IsType(t, errors.SecretNotFoundError(""), err, "should raise an error when the secret is missing from the backend")
+}
@@ -0,0 +1,84 @@
+package sshclient
+
+import (
+	"golang.org/x/crypto/ssh/agent"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PrivateAgent is an in-process ssh-agent (keyring), served on a temporary unix socket. It's used when there is no
+// ssh-agent running (SSH_AUTH_SOCK is unset), eg. in cron jobs, containers or fresh terminal sessions.
+// Keys are only available while the drawbridge process is running.
+type PrivateAgent struct {
+	SocketPath string
+
+	keyring  agent.Agent
+	listener net.Listener
+	tempDir  string
+}
+
+// StartPrivateAgent creates a new keyring and serves it on a unix socket in a private temporary directory.
+func StartPrivateAgent() (*PrivateAgent, error) {
+	tempDir, err := ioutil.TempDir("", "drawbridge-agent")
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chmod(tempDir, 0700)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+
+	socketPath := filepath.Join(tempDir, "agent.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+
+	privateAgent := &PrivateAgent{
+		SocketPath: socketPath,
+		keyring:    agent.NewKeyring(),
+		listener:   listener,
+		tempDir:    tempDir,
+	}
+	go privateAgent.serve()
+	return privateAgent, nil
+}
+
+// Environ returns the current environment, with SSH_AUTH_SOCK pointing to the private agent. Used for child processes.
+func (p *PrivateAgent) Environ() []string {
+	environ := []string{}
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "SSH_AUTH_SOCK=") {
+			environ = append(environ, env)
+		}
+	}
+	return append(environ, "SSH_AUTH_SOCK="+p.SocketPath)
+}
+
+// Close stops serving the agent, removes all keys and deletes the socket.
+func (p *PrivateAgent) Close() error {
+	p.keyring.RemoveAll()
+	p.listener.Close()
+	return os.RemoveAll(p.tempDir)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+func (p *PrivateAgent) serve() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			agent.ServeAgent(p.keyring, conn)
+		}()
+	}
+}
@@ -0,0 +1,33 @@
+package sshclient_test
+
+import (
+	"drawbridge/pkg/sshclient"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh/agent"
+	"net"
+	"os"
+	"testing"
+)
+
+func TestStartPrivateAgent(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	privateAgent, err := sshclient.StartPrivateAgent()
+	require.NoError(t, err)
+
+	//test
+	conn, err := net.Dial("unix", privateAgent.SocketPath)
+	require.NoError(t, err)
+	defer conn.Close()
+	keys, err := agent.NewClient(conn).List()
+
+	//assert
+	require.NoError(t, err, "should serve ssh-agent protocol on socket")
+	require.Empty(t, keys, "should start with an empty keyring")
+	require.Contains(t, privateAgent.Environ(), "SSH_AUTH_SOCK="+privateAgent.SocketPath, "should set SSH_AUTH_SOCK in environment")
+
+	require.NoError(t, privateAgent.Close())
+	_, err = os.Stat(privateAgent.SocketPath)
+	require.True(t, os.IsNotExist(err), "should remove socket when closed")
+}
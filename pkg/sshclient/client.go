@@ -0,0 +1,197 @@
+package sshclient
+
+import (
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"log"
+	"net"
+	"os/user"
+	"strings"
+	"time"
+)
+
+const BastionHostAlias = "bastion"
+
+// Client is an in-process ssh connection to a host defined in a Drawbridge managed ssh config. When connecting to an
+// internal host, the connection is tunneled through the bastion (and the bastion connection is kept open).
+type Client struct {
+	*ssh.Client
+	HostConfig HostConfig
+
+	agent agent.Agent
+	hops  []*ssh.Client
+}
+
+// Dial connects to the bastion host defined in the ssh config (walking its ProxyJump chain, if any), and then (if
+// destHostname is not empty) hops through the bastion to the internal host. All authentication is done via the
+// provided ssh-agent.
+func Dial(sshConfig *Config, destHostname string, agentClient agent.Agent) (*Client, error) {
+	client := Client{agent: agentClient}
+
+	var jumpClient *ssh.Client
+	for _, jumpConfig := range sshConfig.JumpHosts(BastionHostAlias) {
+		var err error
+		jumpClient, err = client.dialHop(jumpClient, jumpConfig, jumpConfig.Address())
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	bastionConfig := sshConfig.Host(BastionHostAlias)
+	bastionClient, err := client.dialHop(jumpClient, bastionConfig, bastionConfig.Address())
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	client.Client = bastionClient
+	client.HostConfig = bastionConfig
+
+	if len(destHostname) > 0 {
+		// the `bastion+*` block in the config template contains the user & options for internal hosts.
+		destConfig := sshConfig.Host(fmt.Sprintf("%v+%v", BastionHostAlias, destHostname))
+		destClient, err := client.dialHop(bastionClient, destConfig, net.JoinHostPort(destHostname, destConfig.Port()))
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		client.Client = destClient
+		client.HostConfig = destConfig
+	}
+
+	return &client, nil
+}
+
+func (c *Client) Close() error {
+	var err error
+	for i := len(c.hops) - 1; i >= 0; i-- {
+		if closeErr := c.hops[i].Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+func (c *Client) dialHop(parent *ssh.Client, hostConfig HostConfig, address string) (*ssh.Client, error) {
+	if proxyCommand := hostConfig.Get("proxycommand"); len(proxyCommand) > 0 && parent == nil {
+		return nil, errors.SshConfigUnsupportedError(fmt.Sprintf("`%v` uses a ProxyCommand, which is not supported by the native ssh client", hostConfig.Alias))
+	}
+
+	clientConfig, err := c.clientConfig(hostConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// ssh.Dial only returns a string representation of host key errors, keep the original (typed) error. Host keys are
+	// checked using the same name as the ssh binary, so both share the known_hosts entries (internal hosts are dialed
+	// by their real name, but recorded under their `bastion+<host>` alias).
+	var hostKeyErr error
+	hostKeyCallback := clientConfig.HostKeyCallback
+	knownHostsAddress := hostConfig.KnownHostsAddress()
+	clientConfig.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		hostKeyErr = hostKeyCallback(knownHostsAddress, remote, key)
+		return hostKeyErr
+	}
+
+	log.Printf("Connecting to %v (%v)", hostConfig.Alias, address)
+
+	var hopClient *ssh.Client
+	if parent == nil {
+		hopClient, err = ssh.Dial("tcp", address, clientConfig)
+		if hostKeyErr != nil {
+			return nil, hostKeyErr
+		} else if err != nil {
+			return nil, err
+		}
+	} else {
+		conn, err := parent.Dial("tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		sshConn, chans, reqs, err := ssh.NewClientConn(conn, address, clientConfig)
+		if err != nil {
+			conn.Close()
+			if hostKeyErr != nil {
+				return nil, hostKeyErr
+			}
+			return nil, err
+		}
+		hopClient = ssh.NewClient(sshConn, chans, reqs)
+	}
+
+	c.hops = append(c.hops, hopClient)
+	return hopClient, nil
+}
+
+func (c *Client) clientConfig(hostConfig HostConfig) (*ssh.ClientConfig, error) {
+	username := hostConfig.Get("user")
+	if len(username) == 0 {
+		currentUser, err := user.Current()
+		if err != nil {
+			return nil, err
+		}
+		username = currentUser.Username
+	}
+
+	hostKeyCallback, err := hostKeyCallback(hostConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssh.ClientConfig{
+		User:            username,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeysCallback(c.agent.Signers)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         10 * time.Second,
+	}, nil
+}
+
+func hostKeyCallback(hostConfig HostConfig) (ssh.HostKeyCallback, error) {
+	strictHostKeyChecking := strings.ToLower(hostConfig.Get("stricthostkeychecking"))
+	if strictHostKeyChecking == "no" {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	knownHostsFiles := knownHostsFiles(hostConfig)
+	if len(knownHostsFiles) == 0 {
+		knownHostsFiles = append(knownHostsFiles, "~/.ssh/known_hosts")
+	}
+
+	// new host keys are recorded in the first known_hosts file (like ssh).
+	if strictHostKeyChecking == "accept-new" {
+		return TofuHostKeyCallback(knownHostsFiles[0]), nil
+	} else if len(knownHostsFiles) == 1 {
+		return StrictHostKeyCallback(knownHostsFiles[0]), nil
+	}
+
+	existingKnownHostsFiles := []string{}
+	for _, knownHostsFile := range knownHostsFiles {
+		expandedPath, err := utils.ExpandPath(knownHostsFile)
+		if err != nil {
+			return nil, err
+		}
+		if utils.FileExists(expandedPath) {
+			existingKnownHostsFiles = append(existingKnownHostsFiles, expandedPath)
+		}
+	}
+	return knownhosts.New(existingKnownHostsFiles...)
+}
+
+// knownHostsFiles returns the UserKnownHostsFile paths for the host, ignoring /dev/null.
+func knownHostsFiles(hostConfig HostConfig) []string {
+	knownHostsFiles := []string{}
+	for _, knownHostsFile := range strings.Fields(hostConfig.Get("userknownhostsfile")) {
+		if knownHostsFile == "/dev/null" {
+			continue
+		}
+		knownHostsFiles = append(knownHostsFiles, knownHostsFile)
+	}
+	return knownHostsFiles
+}
@@ -0,0 +1,207 @@
+package sshclient
+
+import (
+	"bufio"
+	"drawbridge/pkg/utils"
+	"net"
+	"os"
+	"path"
+	"strings"
+)
+
+// Config is a minimal, read-only representation of a rendered ssh config file (the output of a config_template).
+// It only understands the subset of ssh_config(5) that Drawbridge templates generate.
+type Config struct {
+	FilePath string
+	blocks   []hostBlock
+}
+
+type hostBlock struct {
+	patterns []string
+	options  [][2]string
+}
+
+// HostConfig contains the resolved options for a single Host alias, following the ssh_config(5) rule that the
+// first obtained value for each option is used.
+type HostConfig struct {
+	Alias   string
+	options map[string][]string
+}
+
+func ParseConfigFile(configFilePath string) (*Config, error) {
+	configFilePath, err := utils.ExpandPath(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	configFile, err := os.Open(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer configFile.Close()
+
+	sshConfig := Config{FilePath: configFilePath}
+
+	//options declared before the first Host line apply to every host.
+	currentBlock := hostBlock{patterns: []string{"*"}}
+
+	scanner := bufio.NewScanner(configFile)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value := splitConfigLine(line)
+		if key == "host" {
+			sshConfig.blocks = append(sshConfig.blocks, currentBlock)
+			currentBlock = hostBlock{patterns: strings.Fields(value)}
+			continue
+		}
+		currentBlock.options = append(currentBlock.options, [2]string{key, value})
+	}
+	sshConfig.blocks = append(sshConfig.blocks, currentBlock)
+
+	return &sshConfig, scanner.Err()
+}
+
+func (c *Config) Host(alias string) HostConfig {
+	hostConfig := HostConfig{
+		Alias:   alias,
+		options: map[string][]string{},
+	}
+
+	for _, block := range c.blocks {
+		if !block.matches(alias) {
+			continue
+		}
+		for _, option := range block.options {
+			key, value := option[0], option[1]
+			if _, ok := hostConfig.options[key]; ok && !isMultiValueOption(key) {
+				continue
+			}
+			hostConfig.options[key] = append(hostConfig.options[key], value)
+		}
+	}
+	return hostConfig
+}
+
+// JumpHosts returns the resolved config for every host in the ProxyJump chain of the specified alias, outermost first.
+// Each ProxyJump entry has the format `[user@]host[:port]`, and inherits any options declared for the host in the config.
+func (c *Config) JumpHosts(alias string) []HostConfig {
+	proxyJump := c.Host(alias).Get("proxyjump")
+	if len(proxyJump) == 0 || strings.ToLower(proxyJump) == "none" {
+		return []HostConfig{}
+	}
+
+	jumpHosts := []HostConfig{}
+	for _, jumpHost := range strings.Split(proxyJump, ",") {
+		jumpHost = strings.TrimSpace(jumpHost)
+
+		username := ""
+		if atIndex := strings.LastIndex(jumpHost, "@"); atIndex >= 0 {
+			username = jumpHost[:atIndex]
+			jumpHost = jumpHost[atIndex+1:]
+		}
+		port := ""
+		if host, hostPort, err := net.SplitHostPort(jumpHost); err == nil {
+			jumpHost = host
+			port = hostPort
+		}
+
+		resolved := c.Host(jumpHost)
+		options := map[string][]string{}
+		for key, values := range resolved.options {
+			options[key] = values
+		}
+		// values in the ProxyJump entry take precedence over the config.
+		if len(username) > 0 {
+			options["user"] = []string{username}
+		}
+		if len(port) > 0 {
+			options["port"] = []string{port}
+		}
+		jumpHosts = append(jumpHosts, HostConfig{Alias: jumpHost, options: options})
+	}
+	return jumpHosts
+}
+
+// Get returns the first value for the specified (case-insensitive) option, or an empty string.
+func (h HostConfig) Get(key string) string {
+	values := h.options[strings.ToLower(key)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// GetAll returns all values for options that can be specified multiple times (eg. LocalForward)
+func (h HostConfig) GetAll(key string) []string {
+	return h.options[strings.ToLower(key)]
+}
+
+func (h HostConfig) Hostname() string {
+	if hostname := h.Get("hostname"); len(hostname) > 0 {
+		return hostname
+	}
+	return h.Alias
+}
+
+func (h HostConfig) Port() string {
+	if port := h.Get("port"); len(port) > 0 {
+		return port
+	}
+	return "22"
+}
+
+func (h HostConfig) Address() string {
+	return net.JoinHostPort(h.Hostname(), h.Port())
+}
+
+// KnownHostsAddress returns the name the host key is recorded under in known_hosts files, like ssh: the HostKeyAlias
+// (without port), or the host name. Internal hosts don't have a HostName, so they're recorded under their alias
+// (eg. `bastion+database-1`).
+func (h HostConfig) KnownHostsAddress() string {
+	if hostKeyAlias := h.Get("hostkeyalias"); len(hostKeyAlias) > 0 {
+		return net.JoinHostPort(hostKeyAlias, "22")
+	}
+	return h.Address()
+}
+
+func (h HostConfig) IsEnabled(key string) bool {
+	return strings.ToLower(h.Get(key)) == "yes"
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+func (b hostBlock) matches(alias string) bool {
+	matched := false
+	for _, pattern := range b.patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		if ok, _ := path.Match(pattern, alias); ok {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// ssh_config allows both `Key Value` and `Key=Value` forms.
+func splitConfigLine(line string) (string, string) {
+	separator := strings.IndexAny(line, " \t=")
+	if separator == -1 {
+		return strings.ToLower(line), ""
+	}
+	key := strings.ToLower(line[:separator])
+	value := strings.TrimLeft(line[separator:], " \t=")
+	return key, strings.Trim(value, `"`)
+}
+
+func isMultiValueOption(key string) bool {
+	return key == "localforward" || key == "remoteforward" || key == "identityfile"
+}
@@ -0,0 +1,109 @@
+package sshclient_test
+
+import (
+	"drawbridge/pkg/sshclient"
+	"github.com/stretchr/testify/require"
+	"path"
+	"testing"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	t.Parallel()
+
+	//test
+	sshConfig, err := sshclient.ParseConfigFile(path.Join("testdata", "ssh_config"))
+
+	//assert
+	require.NoError(t, err, "should parse rendered ssh config")
+	bastionConfig := sshConfig.Host("bastion")
+	require.Equal(t, "bastion1.idle.us-east-1.apptestexample.com", bastionConfig.Hostname(), "should populate hostname")
+	require.Equal(t, "22", bastionConfig.Port(), "should default to port 22")
+	require.Equal(t, "bastion1.idle.us-east-1.apptestexample.com:22", bastionConfig.Address(), "should generate dial address")
+	require.Equal(t, "cloud-user", bastionConfig.Get("User"), "option lookup should be case-insensitive")
+	require.Equal(t, "/dev/null", bastionConfig.Get("userknownhostsfile"), "should support key=value syntax")
+	require.Equal(t, []string{"localhost:48275 localhost:8080"}, bastionConfig.GetAll("localforward"), "should populate forwards")
+	require.True(t, bastionConfig.IsEnabled("forwardagent"), "should inherit global options")
+	require.Empty(t, bastionConfig.Get("proxycommand"), "should not inherit options from wildcard host")
+}
+
+func TestParseConfigFile_WildcardHost(t *testing.T) {
+	t.Parallel()
+
+	//test
+	sshConfig, err := sshclient.ParseConfigFile(path.Join("testdata", "ssh_config"))
+	destConfig := sshConfig.Host("bastion+database-1")
+
+	//assert
+	require.NoError(t, err, "should parse rendered ssh config")
+	require.Equal(t, "bastion+database-1", destConfig.Hostname(), "should default hostname to alias")
+	require.Equal(t, "2222", destConfig.Port(), "should populate port from wildcard host")
+	require.Equal(t, "cloud-user", destConfig.Get("user"), "should populate user from wildcard host")
+	require.NotEmpty(t, destConfig.Get("proxycommand"), "should populate proxycommand from wildcard host")
+}
+
+func TestHostConfig_KnownHostsAddress(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	sshConfig, err := sshclient.ParseConfigFile(path.Join("testdata", "ssh_config"))
+	require.NoError(t, err)
+
+	//test
+	bastionAddress := sshConfig.Host("bastion").KnownHostsAddress()
+	destAddress := sshConfig.Host("bastion+database-1").KnownHostsAddress()
+	aliasAddress := sshConfig.Host("bastion+cache-1").KnownHostsAddress()
+
+	//assert
+	require.Equal(t, "bastion1.idle.us-east-1.apptestexample.com:22", bastionAddress, "should use the hostname")
+	require.Equal(t, "bastion+database-1:2222", destAddress, "should use the alias of internal hosts, like ssh")
+	require.Equal(t, "cache.internal:22", aliasAddress, "should use the HostKeyAlias, without port")
+}
+
+func TestParseConfigFile_InvalidPath(t *testing.T) {
+	t.Parallel()
+
+	//test
+	_, err := sshclient.ParseConfigFile(path.Join("testdata", "does_not_exist"))
+
+	//assert
+	require.Error(t, err, "should raise an error when config file is missing")
+}
+
+func TestParseForward(t *testing.T) {
+	t.Parallel()
+
+	//test
+	localAddress, remoteAddress, err := sshclient.ParseForward("1234 localhost:8080")
+
+	//assert
+	require.NoError(t, err, "should parse forward")
+	require.Equal(t, "localhost:1234", localAddress, "should default local bind address to localhost")
+	require.Equal(t, "localhost:8080", remoteAddress, "should populate remote address")
+}
+
+func TestConfig_JumpHosts(t *testing.T) {
+	t.Parallel()
+
+	//test
+	sshConfig, err := sshclient.ParseConfigFile(path.Join("testdata", "ssh_config_jump"))
+	jumpHosts := sshConfig.JumpHosts("bastion")
+
+	//assert
+	require.NoError(t, err, "should parse rendered ssh config")
+	require.Len(t, jumpHosts, 2, "should populate every host in the jump chain")
+	require.Equal(t, "gateway.corp.example.com:22", jumpHosts[0].Address(), "should populate outermost jump host first")
+	require.Equal(t, "corp-user", jumpHosts[0].Get("user"), "should inherit options declared for jump host")
+	require.Equal(t, "regional.example.com:2222", jumpHosts[1].Address(), "should populate port from jump host entry")
+	require.Equal(t, "jump-user", jumpHosts[1].Get("user"), "should populate user from jump host entry")
+}
+
+func TestConfig_JumpHosts_None(t *testing.T) {
+	t.Parallel()
+
+	//test
+	sshConfig, err := sshclient.ParseConfigFile(path.Join("testdata", "ssh_config"))
+
+	//assert
+	require.NoError(t, err, "should parse rendered ssh config")
+	require.Empty(t, sshConfig.JumpHosts("bastion"), "should return empty chain when ProxyJump is not set")
+}
@@ -0,0 +1,94 @@
+package sshclient
+
+import (
+	"drawbridge/pkg/errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+)
+
+// LocalForward listens on the local address, and tunnels every accepted connection to the remote address (resolved
+// on the connected host). Equivalent to the ssh `LocalForward` option. Closing the returned listener stops forwarding.
+func (c *Client) LocalForward(localAddress string, remoteAddress string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", localAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			localConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				remoteConn, err := c.Dial("tcp", remoteAddress)
+				if err != nil {
+					log.Printf("Could not forward %v to %v: %v", localAddress, remoteAddress, err)
+					localConn.Close()
+					return
+				}
+				Pipe(localConn, remoteConn)
+			}()
+		}
+	}()
+	return listener, nil
+}
+
+// StartLocalForwards starts every `LocalForward` declared for the connected host in the ssh config.
+func (c *Client) StartLocalForwards() ([]net.Listener, error) {
+	listeners := []net.Listener{}
+	for _, forward := range c.HostConfig.GetAll("localforward") {
+		localAddress, remoteAddress, err := ParseForward(forward)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+
+		listener, err := c.LocalForward(localAddress, remoteAddress)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		log.Printf("Forwarding %v to %v (via %v)", localAddress, remoteAddress, c.HostConfig.Alias)
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// ParseForward converts a `LocalForward` value (eg. `localhost:1234 localhost:8080` or `1234 localhost:8080`) into a
+// local & remote address pair.
+func ParseForward(forward string) (string, string, error) {
+	parts := strings.Fields(forward)
+	if len(parts) != 2 {
+		return "", "", errors.SshConfigUnsupportedError(fmt.Sprintf("Invalid forward specification: `%v`", forward))
+	}
+
+	localAddress := parts[0]
+	if !strings.Contains(localAddress, ":") {
+		localAddress = net.JoinHostPort("localhost", localAddress)
+	}
+	return localAddress, parts[1], nil
+}
+
+// Pipe copies data in both directions between the connections, and closes both when either side is done.
+func Pipe(a net.Conn, b net.Conn) {
+	done := make(chan struct{}, 2)
+	copyConn := func(dst net.Conn, src net.Conn) {
+		io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go copyConn(a, b)
+	go copyConn(b, a)
+	<-done
+	a.Close()
+	b.Close()
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, listener := range listeners {
+		listener.Close()
+	}
+}
@@ -0,0 +1,242 @@
+package sshclient
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha1"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/utils"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// KnownHost is a single entry in a known_hosts file.
+type KnownHost struct {
+	Hosts       []string
+	KeyType     string
+	Fingerprint string
+	Hashed      bool
+}
+
+// knownHostsMutex serializes writes to known_hosts files, multiple hops may be verified concurrently.
+var knownHostsMutex sync.Mutex
+
+// ListKnownHosts returns every entry in the known_hosts file. A missing file has no entries.
+func ListKnownHosts(knownHostsFilePath string) ([]KnownHost, error) {
+	lines, err := readKnownHostsLines(knownHostsFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	knownHostsList := []KnownHost{}
+	for _, line := range lines {
+		_, hosts, publicKey, _, _, err := ssh.ParseKnownHosts([]byte(line))
+		if err != nil {
+			continue
+		}
+		knownHost := KnownHost{
+			Hosts:       hosts,
+			KeyType:     publicKey.Type(),
+			Fingerprint: ssh.FingerprintSHA256(publicKey),
+		}
+		for _, host := range hosts {
+			if strings.HasPrefix(host, "|1|") {
+				knownHost.Hashed = true
+			}
+		}
+		knownHostsList = append(knownHostsList, knownHost)
+	}
+	return knownHostsList, nil
+}
+
+// ForgetHost removes every entry for the host (`host` or `host:port`) from the known_hosts file, and returns the number
+// of removed entries.
+func ForgetHost(knownHostsFilePath string, host string) (int, error) {
+	knownHostsMutex.Lock()
+	defer knownHostsMutex.Unlock()
+
+	lines, err := readKnownHostsLines(knownHostsFilePath)
+	if err != nil {
+		return 0, err
+	}
+
+	normalizedHost := normalizeKnownHost(host)
+	removed := 0
+	remaining := []string{}
+	for _, line := range lines {
+		if knownHostsLineMatches(line, normalizedHost) {
+			removed++
+			continue
+		}
+		remaining = append(remaining, line)
+	}
+
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, writeKnownHostsLines(knownHostsFilePath, remaining)
+}
+
+// PinHost replaces any existing entries for the host (`host` or `host:port`) with the provided public key.
+func PinHost(knownHostsFilePath string, host string, publicKey ssh.PublicKey) error {
+	_, err := ForgetHost(knownHostsFilePath, host)
+	if err != nil {
+		return err
+	}
+	return appendKnownHost(knownHostsFilePath, normalizeKnownHost(host), publicKey)
+}
+
+// TofuHostKeyCallback verifies host keys against the known_hosts file. Keys for unknown hosts are trusted on first use
+// and recorded in the file. If a host presents a different key than the recorded one, a HostKeyChangedError is returned.
+func TofuHostKeyCallback(knownHostsFilePath string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := checkKnownHost(knownHostsFilePath, hostname, remote, key)
+		if keyErr, ok := err.(*knownhosts.KeyError); ok && len(keyErr.Want) == 0 {
+			log.Printf("Permanently added %v (%v) to the list of known hosts (%v)", hostname, ssh.FingerprintSHA256(key), knownHostsFilePath)
+			return appendKnownHost(knownHostsFilePath, knownhosts.Normalize(hostname), key)
+		}
+		return err
+	}
+}
+
+// StrictHostKeyCallback verifies host keys against the known_hosts file. Unknown hosts are rejected.
+func StrictHostKeyCallback(knownHostsFilePath string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return checkKnownHost(knownHostsFilePath, hostname, remote, key)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+func checkKnownHost(knownHostsFilePath string, hostname string, remote net.Addr, key ssh.PublicKey) error {
+	knownHostsFilePath, err := utils.ExpandPath(knownHostsFilePath)
+	if err != nil {
+		return err
+	}
+
+	if !utils.FileExists(knownHostsFilePath) {
+		return &knownhosts.KeyError{}
+	}
+
+	callback, err := knownhosts.New(knownHostsFilePath)
+	if err != nil {
+		return err
+	}
+
+	err = callback(hostname, remote, key)
+	if keyErr, ok := err.(*knownhosts.KeyError); ok && len(keyErr.Want) > 0 {
+		return errors.HostKeyChangedError(fmt.Sprintf(
+			"The host key for %v has changed (now %v %v). This could mean someone is intercepting the connection. If the change is expected, run `drawbridge hostkeys forget` for this host (recorded in %v, line %v)",
+			hostname, key.Type(), ssh.FingerprintSHA256(key), keyErr.Want[0].Filename, keyErr.Want[0].Line,
+		))
+	}
+	return err
+}
+
+func appendKnownHost(knownHostsFilePath string, host string, publicKey ssh.PublicKey) error {
+	knownHostsFilePath, err := utils.ExpandPath(knownHostsFilePath)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(knownHostsFilePath), 0700)
+	if err != nil {
+		return err
+	}
+
+	knownHostsFile, err := os.OpenFile(knownHostsFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer knownHostsFile.Close()
+
+	_, err = fmt.Fprintln(knownHostsFile, knownhosts.Line([]string{host}, publicKey))
+	return err
+}
+
+func readKnownHostsLines(knownHostsFilePath string) ([]string, error) {
+	knownHostsFilePath, err := utils.ExpandPath(knownHostsFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	knownHostsFile, err := os.Open(knownHostsFilePath)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer knownHostsFile.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(knownHostsFile)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+func writeKnownHostsLines(knownHostsFilePath string, lines []string) error {
+	knownHostsFilePath, err := utils.ExpandPath(knownHostsFilePath)
+	if err != nil {
+		return err
+	}
+
+	content := ""
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\n"
+	}
+	return utils.FileWrite(knownHostsFilePath, content, 0600, false)
+}
+
+// normalizeKnownHost converts `host` or `host:port` into the known_hosts format (`host` or `[host]:port`)
+func normalizeKnownHost(host string) string {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return knownhosts.Normalize(net.JoinHostPort(host, "22"))
+	}
+	return knownhosts.Normalize(host)
+}
+
+func knownHostsLineMatches(line string, normalizedHost string) bool {
+	_, hosts, _, _, _, err := ssh.ParseKnownHosts([]byte(line))
+	if err != nil {
+		return false
+	}
+
+	for _, host := range hosts {
+		if host == normalizedHost || hashedHostMatches(host, normalizedHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashed entries have the format `|1|base64(salt)|base64(hmac-sha1(salt, host))`
+func hashedHostMatches(entry string, normalizedHost string) bool {
+	parts := strings.Split(entry, "|")
+	if len(parts) != 4 || parts[1] != "1" {
+		return false
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	hash, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(normalizedHost))
+	return hmac.Equal(mac.Sum(nil), hash)
+}
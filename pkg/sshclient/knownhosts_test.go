@@ -0,0 +1,97 @@
+package sshclient_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/sshclient"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+)
+
+func generateHostKey(t *testing.T) ssh.PublicKey {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	require.NoError(t, err)
+	return sshPublicKey
+}
+
+func TestTofuHostKeyCallback(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	knownHostsFilePath := path.Join(parentPath, ".config.known_hosts")
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 22}
+	hostKey := generateHostKey(t)
+	callback := sshclient.TofuHostKeyCallback(knownHostsFilePath)
+
+	//test
+	firstErr := callback("bastion.example.com:22", remote, hostKey)
+	secondErr := callback("bastion.example.com:22", remote, hostKey)
+	changedErr := callback("bastion.example.com:22", remote, generateHostKey(t))
+	knownHostsList, err := sshclient.ListKnownHosts(knownHostsFilePath)
+
+	//assert
+	require.NoError(t, firstErr, "should trust unknown host on first use")
+	require.NoError(t, secondErr, "should trust recorded host key")
+	require.IsType(t, errors.HostKeyChangedError(""), changedErr, "should fail when host key changes")
+	require.NoError(t, err)
+	require.Len(t, knownHostsList, 1, "should record host key on first use")
+	require.Equal(t, []string{"bastion.example.com"}, knownHostsList[0].Hosts)
+	require.Equal(t, ssh.FingerprintSHA256(hostKey), knownHostsList[0].Fingerprint)
+}
+
+func TestForgetHost(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	knownHostsFilePath := path.Join(parentPath, ".config.known_hosts")
+	require.NoError(t, sshclient.PinHost(knownHostsFilePath, "bastion.example.com", generateHostKey(t)))
+	require.NoError(t, sshclient.PinHost(knownHostsFilePath, "gateway.example.com:2222", generateHostKey(t)))
+
+	//test
+	removed, err := sshclient.ForgetHost(knownHostsFilePath, "gateway.example.com:2222")
+	knownHostsList, listErr := sshclient.ListKnownHosts(knownHostsFilePath)
+
+	//assert
+	require.NoError(t, err)
+	require.NoError(t, listErr)
+	require.Equal(t, 1, removed, "should remove host key for host & port")
+	require.Len(t, knownHostsList, 1, "should keep host keys for other hosts")
+	require.Equal(t, []string{"bastion.example.com"}, knownHostsList[0].Hosts)
+}
+
+func TestPinHost(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	knownHostsFilePath := path.Join(parentPath, ".config.known_hosts")
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 22}
+	oldHostKey := generateHostKey(t)
+	newHostKey := generateHostKey(t)
+	require.NoError(t, sshclient.PinHost(knownHostsFilePath, "bastion.example.com", oldHostKey))
+
+	//test
+	err = sshclient.PinHost(knownHostsFilePath, "bastion.example.com", newHostKey)
+	callback := sshclient.StrictHostKeyCallback(knownHostsFilePath)
+
+	//assert
+	require.NoError(t, err)
+	require.NoError(t, callback("bastion.example.com:22", remote, newHostKey), "should trust pinned host key")
+	require.IsType(t, errors.HostKeyChangedError(""), callback("bastion.example.com:22", remote, oldHostKey), "should replace previously recorded host key")
+}
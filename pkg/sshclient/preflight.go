@@ -0,0 +1,85 @@
+package sshclient
+
+import (
+	"drawbridge/pkg/errors"
+	stderrors "errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"strings"
+	"time"
+)
+
+const PreflightTimeout = 5 * time.Second
+
+// Preflight checks that the first host in the bastion chain (the outermost ProxyJump host, or the bastion itself) in a
+// rendered ssh config can be resolved, and is accepting connections on its ssh port, so that users get a clear error
+// instead of an opaque ssh timeout.
+func Preflight(sshConfig *Config, timeout time.Duration) error {
+	firstHopConfig := sshConfig.Host(BastionHostAlias)
+	if jumpHosts := sshConfig.JumpHosts(BastionHostAlias); len(jumpHosts) > 0 {
+		firstHopConfig = jumpHosts[0]
+	}
+
+	// the host is only reachable through a ProxyCommand, we can't check it directly.
+	if len(firstHopConfig.Get("proxycommand")) > 0 {
+		return nil
+	}
+
+	hostname := firstHopConfig.Hostname()
+	if net.ParseIP(hostname) == nil {
+		if _, err := net.LookupHost(hostname); err != nil {
+			return errors.BastionDnsError(fmt.Sprintf("Could not resolve %v hostname %v (%v). Check the answers used to render %v", firstHopConfig.Alias, hostname, err, sshConfig.FilePath))
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", firstHopConfig.Address(), timeout)
+	if err != nil {
+		return errors.BastionUnreachableError(fmt.Sprintf("%v (%v) is not reachable (%v). Check your network/VPN connection", firstHopConfig.Alias, firstHopConfig.Address(), err))
+	}
+	conn.Close()
+
+	return verifyHostKey(firstHopConfig, timeout)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+// verifyHostKey checks the host key against the drawbridge managed known_hosts file (recording it on first use), so that
+// a changed key fails with a HostKeyChangedError before handing off to ssh. The connection is closed as soon as the
+// host key has been checked, before authentication.
+func verifyHostKey(hostConfig HostConfig, timeout time.Duration) error {
+	strictHostKeyChecking := strings.ToLower(hostConfig.Get("stricthostkeychecking"))
+	knownHostsFiles := knownHostsFiles(hostConfig)
+	if (strictHostKeyChecking != "accept-new" && strictHostKeyChecking != "yes") || len(knownHostsFiles) == 0 {
+		return nil
+	}
+
+	hostKeyCallback, err := hostKeyCallback(hostConfig)
+	if err != nil {
+		return err
+	}
+
+	var hostKeyErr error
+	hostKeyChecked := false
+	ssh.Dial("tcp", hostConfig.Address(), &ssh.ClientConfig{
+		User: "drawbridge",
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKeyChecked = true
+			hostKeyErr = hostKeyCallback(hostConfig.KnownHostsAddress(), remote, key)
+			if hostKeyErr != nil {
+				return hostKeyErr
+			}
+			return errHostKeyVerified
+		},
+		Timeout: timeout,
+	})
+
+	if !hostKeyChecked {
+		// not an ssh server (or the handshake failed), let ssh report the problem.
+		return nil
+	}
+	return hostKeyErr
+}
+
+var errHostKeyVerified = stderrors.New("host key verified")
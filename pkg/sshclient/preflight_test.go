@@ -0,0 +1,65 @@
+package sshclient_test
+
+import (
+	"drawbridge/pkg/errors"
+	"drawbridge/pkg/sshclient"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func writeBastionConfig(t *testing.T, dirPath string, address string) *sshclient.Config {
+	host, port, err := net.SplitHostPort(address)
+	require.NoError(t, err)
+
+	configFilePath := path.Join(dirPath, "ssh_config")
+	err = ioutil.WriteFile(configFilePath, []byte("Host bastion\n  Hostname "+host+"\n  Port "+port+"\n"), 0600)
+	require.NoError(t, err)
+
+	sshConfig, err := sshclient.ParseConfigFile(configFilePath)
+	require.NoError(t, err)
+	return sshConfig
+}
+
+func TestPreflight(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	sshConfig := writeBastionConfig(t, parentPath, listener.Addr().String())
+
+	//test
+	err = sshclient.Preflight(sshConfig, time.Second)
+
+	//assert
+	require.NoError(t, err, "should succeed when bastion is accepting connections")
+}
+
+func TestPreflight_Unreachable(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	parentPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(parentPath)
+	sshConfig := writeBastionConfig(t, parentPath, listener.Addr().String())
+	listener.Close()
+
+	//test
+	err = sshclient.Preflight(sshConfig, time.Second)
+
+	//assert
+	require.Error(t, err, "should fail when bastion is not accepting connections")
+	require.IsType(t, errors.BastionUnreachableError(""), err, "should return typed error")
+}
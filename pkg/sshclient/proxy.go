@@ -0,0 +1,191 @@
+package sshclient
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// ProxyServer is a local SOCKS5 & HTTP (CONNECT and absolute-form requests) proxy server. Every proxied connection is
+// opened using Dial, which is usually the Dial method of a Client connected to the bastion.
+type ProxyServer struct {
+	Dial func(network string, address string) (net.Conn, error)
+}
+
+const (
+	socks5Version         = 0x05
+	socks5CmdConnect      = 0x01
+	socks5AddrIPv4        = 0x01
+	socks5AddrDomain      = 0x03
+	socks5AddrIPv6        = 0x04
+	socks5ReplySuccess    = 0x00
+	socks5ReplyHostFailed = 0x04
+	socks5ReplyCmdFailed  = 0x07
+)
+
+// Serve accepts connections on the listener until it is closed.
+func (p *ProxyServer) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go p.handleConn(conn)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+// bufferedConn makes sure that any data read ahead while sniffing the protocol is not lost.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (b bufferedConn) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
+
+func (p *ProxyServer) handleConn(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	clientConn := bufferedConn{Conn: conn, reader: reader}
+
+	firstByte, err := reader.Peek(1)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	if firstByte[0] == socks5Version {
+		err = p.handleSocks5(clientConn)
+	} else {
+		err = p.handleHttp(clientConn)
+	}
+	if err != nil {
+		log.Printf("Proxy error: %v", err)
+		conn.Close()
+	}
+}
+
+// https://tools.ietf.org/html/rfc1928
+func (p *ProxyServer) handleSocks5(clientConn bufferedConn) error {
+	// greeting: version, number of auth methods, auth methods. We only support "no authentication"
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(clientConn, header); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(clientConn, make([]byte, header[1])); err != nil {
+		return err
+	}
+	if _, err := clientConn.Write([]byte{socks5Version, 0x00}); err != nil {
+		return err
+	}
+
+	// request: version, command, reserved, address type
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(clientConn, request); err != nil {
+		return err
+	}
+
+	var host string
+	switch request[3] {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		addrLength := net.IPv4len
+		if request[3] == socks5AddrIPv6 {
+			addrLength = net.IPv6len
+		}
+		addr := make([]byte, addrLength)
+		if _, err := io.ReadFull(clientConn, addr); err != nil {
+			return err
+		}
+		host = net.IP(addr).String()
+	case socks5AddrDomain:
+		domainLength := make([]byte, 1)
+		if _, err := io.ReadFull(clientConn, domainLength); err != nil {
+			return err
+		}
+		domain := make([]byte, domainLength[0])
+		if _, err := io.ReadFull(clientConn, domain); err != nil {
+			return err
+		}
+		host = string(domain)
+	default:
+		return fmt.Errorf("unsupported SOCKS5 address type: %v", request[3])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(clientConn, port); err != nil {
+		return err
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+
+	if request[1] != socks5CmdConnect {
+		socks5Reply(clientConn, socks5ReplyCmdFailed)
+		return fmt.Errorf("unsupported SOCKS5 command: %v", request[1])
+	}
+
+	remoteConn, err := p.Dial("tcp", address)
+	if err != nil {
+		socks5Reply(clientConn, socks5ReplyHostFailed)
+		return err
+	}
+
+	if err := socks5Reply(clientConn, socks5ReplySuccess); err != nil {
+		remoteConn.Close()
+		return err
+	}
+
+	Pipe(clientConn, remoteConn)
+	return nil
+}
+
+func socks5Reply(clientConn net.Conn, status byte) error {
+	// version, status, reserved, address type (IPv4), bound address (0.0.0.0:0)
+	_, err := clientConn.Write([]byte{socks5Version, status, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+func (p *ProxyServer) handleHttp(clientConn bufferedConn) error {
+	req, err := http.ReadRequest(clientConn.reader)
+	if err != nil {
+		return err
+	}
+
+	address := req.Host
+	if req.Method != http.MethodConnect && req.URL.Host != "" {
+		address = req.URL.Host
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "80")
+	}
+
+	remoteConn, err := p.Dial("tcp", address)
+	if err != nil {
+		fmt.Fprintf(clientConn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return err
+	}
+
+	if req.Method == http.MethodConnect {
+		_, err = fmt.Fprintf(clientConn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	} else {
+		// plain http request, forward it to the destination. The connection is closed after the response, so that
+		// the client doesn't reuse it for a different host.
+		req.Header.Del("Proxy-Connection")
+		req.Header.Del("Proxy-Authorization")
+		req.Close = true
+		err = req.Write(remoteConn)
+	}
+	if err != nil {
+		remoteConn.Close()
+		return err
+	}
+
+	Pipe(clientConn, remoteConn)
+	return nil
+}
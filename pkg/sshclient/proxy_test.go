@@ -0,0 +1,134 @@
+package sshclient_test
+
+import (
+	"bufio"
+	"drawbridge/pkg/sshclient"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func startEchoServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(conn, conn)
+		}
+	}()
+	return listener
+}
+
+func startProxyServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	proxyServer := sshclient.ProxyServer{Dial: net.Dial}
+	go proxyServer.Serve(listener)
+	return listener
+}
+
+func TestProxyServer_Socks5(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	echoListener := startEchoServer(t)
+	defer echoListener.Close()
+	proxyListener := startProxyServer(t)
+	defer proxyListener.Close()
+
+	echoAddr := echoListener.Addr().(*net.TCPAddr)
+
+	conn, err := net.Dial("tcp", proxyListener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	//test
+	_, err = conn.Write([]byte{0x05, 0x01, 0x00})
+	require.NoError(t, err)
+	greeting := make([]byte, 2)
+	_, err = io.ReadFull(conn, greeting)
+	require.NoError(t, err)
+
+	request := []byte{0x05, 0x01, 0x00, 0x01}
+	request = append(request, echoAddr.IP.To4()...)
+	request = append(request, byte(echoAddr.Port>>8), byte(echoAddr.Port&0xff))
+	_, err = conn.Write(request)
+	require.NoError(t, err)
+	reply := make([]byte, 10)
+	_, err = io.ReadFull(conn, reply)
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	echo := make([]byte, 4)
+	_, err = io.ReadFull(conn, echo)
+
+	//assert
+	require.NoError(t, err, "should read data tunneled through proxy")
+	require.Equal(t, []byte{0x05, 0x00}, greeting, "should accept no authentication")
+	require.Equal(t, byte(0x00), reply[1], "should successfully connect to destination")
+	require.Equal(t, "ping", string(echo), "should tunnel data to destination")
+}
+
+func TestProxyServer_HttpConnect(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	echoListener := startEchoServer(t)
+	defer echoListener.Close()
+	proxyListener := startProxyServer(t)
+	defer proxyListener.Close()
+
+	conn, err := net.Dial("tcp", proxyListener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	//test
+	_, err = conn.Write([]byte("CONNECT " + echoListener.Addr().String() + " HTTP/1.1\r\nHost: " + echoListener.Addr().String() + "\r\n\r\n"))
+	require.NoError(t, err)
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	echo := make([]byte, 4)
+	_, err = io.ReadFull(reader, echo)
+
+	//assert
+	require.NoError(t, err, "should read data tunneled through proxy")
+	require.Equal(t, 200, resp.StatusCode, "should establish tunnel")
+	require.Equal(t, "ping", string(echo), "should tunnel data to destination")
+}
+
+func TestProxyServer_HttpConnect_DialError(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	proxyListener := startProxyServer(t)
+	defer proxyListener.Close()
+
+	unusedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	unusedAddr := unusedListener.Addr().String()
+	unusedListener.Close()
+
+	conn, err := net.Dial("tcp", proxyListener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	//test
+	_, err = conn.Write([]byte("CONNECT " + unusedAddr + " HTTP/1.1\r\nHost: " + unusedAddr + "\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+
+	//assert
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadGateway, resp.StatusCode, "should return bad gateway when destination is unreachable")
+}
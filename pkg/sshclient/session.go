@@ -0,0 +1,84 @@
+package sshclient
+
+import (
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/terminal"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Shell opens an interactive login shell on the connected host, attaching the current terminal (via a PTY) to the
+// remote session. Blocks until the remote shell exits.
+func (c *Client) Shell() error {
+	if c.HostConfig.IsEnabled("forwardagent") {
+		err := agent.ForwardToAgent(c.Client, c.agent)
+		if err != nil {
+			return err
+		}
+	}
+
+	session, err := c.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	if c.HostConfig.IsEnabled("forwardagent") {
+		err = agent.RequestAgentForwarding(session)
+		if err != nil {
+			return err
+		}
+	}
+
+	stdinFd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(stdinFd) {
+		originalState, err := terminal.MakeRaw(stdinFd)
+		if err != nil {
+			return err
+		}
+		defer terminal.Restore(stdinFd, originalState)
+
+		width, height, err := terminal.GetSize(stdinFd)
+		if err != nil {
+			return err
+		}
+
+		termType := os.Getenv("TERM")
+		if len(termType) == 0 {
+			termType = "xterm-256color"
+		}
+
+		err = session.RequestPty(termType, height, width, ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		})
+		if err != nil {
+			return err
+		}
+
+		// keep the remote PTY size in sync with the local terminal.
+		resize := make(chan os.Signal, 1)
+		signal.Notify(resize, syscall.SIGWINCH)
+		defer signal.Stop(resize)
+		go func() {
+			for range resize {
+				if width, height, err := terminal.GetSize(stdinFd); err == nil {
+					session.WindowChange(height, width)
+				}
+			}
+		}()
+	}
+
+	session.Stdin = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+
+	err = session.Shell()
+	if err != nil {
+		return err
+	}
+	return session.Wait()
+}
@@ -0,0 +1,148 @@
+package sshclient
+
+import (
+	"drawbridge/pkg/errors"
+	"golang.org/x/crypto/ssh/agent"
+	"log"
+	"time"
+)
+
+const (
+	TunnelStatusConnecting   = "connecting"
+	TunnelStatusConnected    = "connected"
+	TunnelStatusReconnecting = "reconnecting"
+
+	minReconnectBackoff = 1 * time.Second
+	maxReconnectBackoff = 1 * time.Minute
+	keepAliveInterval   = 15 * time.Second
+)
+
+// Supervisor keeps the bastion connection (and all of its `LocalForward` listeners) for a Drawbridge managed ssh config
+// open, reconnecting with exponential backoff whenever the connection is lost (eg. when the laptop sleeps).
+type Supervisor struct {
+	ConfigFilePath string
+	Agent          agent.Agent
+
+	// called whenever the tunnel status changes. err is populated when the tunnel was disconnected because of an error.
+	OnStatusChange func(status string, err error)
+}
+
+// Run blocks until the stop channel is closed.
+func (s *Supervisor) Run(stop <-chan struct{}) {
+	backoff := time.Duration(0)
+	for {
+		s.statusChange(TunnelStatusConnecting, nil)
+		connectedAt := time.Now()
+		err := s.runOnce(stop)
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		// connections that were healthy for a while should retry quickly.
+		if time.Since(connectedAt) > maxReconnectBackoff {
+			backoff = 0
+		}
+		backoff = NextBackoff(backoff)
+
+		log.Printf("Tunnel for %v disconnected (%v). Reconnecting in %v", s.ConfigFilePath, err, backoff)
+		s.statusChange(TunnelStatusReconnecting, err)
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// NextBackoff doubles the current backoff duration, bounded by the min & max reconnect backoff.
+func NextBackoff(current time.Duration) time.Duration {
+	next := current * 2
+	if next < minReconnectBackoff {
+		return minReconnectBackoff
+	} else if next > maxReconnectBackoff {
+		return maxReconnectBackoff
+	}
+	return next
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Helpers
+
+func (s *Supervisor) statusChange(status string, err error) {
+	if s.OnStatusChange != nil {
+		s.OnStatusChange(status, err)
+	}
+}
+
+// runOnce connects to the bastion and starts all forwards, returning when the connection is lost.
+func (s *Supervisor) runOnce(stop <-chan struct{}) error {
+	// re-read the config every time, in case it was re-created.
+	sshConfig, err := ParseConfigFile(s.ConfigFilePath)
+	if err != nil {
+		return err
+	}
+
+	client, err := Dial(sshConfig, "", s.Agent)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	listeners, err := client.StartLocalForwards()
+	if err != nil {
+		return err
+	}
+	defer closeListeners(listeners)
+
+	s.statusChange(TunnelStatusConnected, nil)
+
+	disconnected := make(chan error, 2)
+	go func() {
+		disconnected <- client.Wait()
+	}()
+	go func() {
+		disconnected <- client.keepAlive(stop)
+	}()
+
+	select {
+	case <-stop:
+		return nil
+	case err := <-disconnected:
+		return err
+	}
+}
+
+// keepAlive periodically sends keepalive requests, so that dead connections (that never receive a TCP reset) are
+// detected and closed.
+func (c *Client) keepAlive(stop <-chan struct{}) error {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		reply := make(chan error, 1)
+		go func() {
+			_, _, err := c.SendRequest("keepalive@openssh.com", true, nil)
+			reply <- err
+		}()
+
+		select {
+		case err := <-reply:
+			if err != nil {
+				return err
+			}
+		case <-time.After(keepAliveInterval):
+			c.Close()
+			return errors.TunnelDisconnectedError("keepalive request timed out")
+		}
+	}
+}
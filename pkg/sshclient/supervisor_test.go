@@ -0,0 +1,17 @@
+package sshclient_test
+
+import (
+	"drawbridge/pkg/sshclient"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNextBackoff(t *testing.T) {
+	t.Parallel()
+
+	//assert
+	require.Equal(t, 1*time.Second, sshclient.NextBackoff(0), "should start with minimum backoff")
+	require.Equal(t, 4*time.Second, sshclient.NextBackoff(2*time.Second), "should double backoff")
+	require.Equal(t, 1*time.Minute, sshclient.NextBackoff(45*time.Second), "should limit backoff to maximum")
+}
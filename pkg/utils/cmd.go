@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"sync"
 )
 
 //http://craigwickesser.com/2015/02/golang-cmd-with-custom-environment/
@@ -82,3 +83,27 @@ func CmdExec(cmdName string, cmdArgs []string, workingDir string, environ []stri
 	}
 	return nil
 }
+
+// ParallelExec calls fn for every item, running at most `parallelism` calls concurrently. Returns the error (or nil)
+// returned for each item, in the same order as items.
+func ParallelExec(items []string, parallelism int, fn func(item string) error) []error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	results := make([]error, len(items))
+	semaphore := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+
+	for i, item := range items {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(index int, item string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[index] = fn(item)
+		}(i, item)
+	}
+	wg.Wait()
+	return results
+}
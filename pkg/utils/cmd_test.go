@@ -2,9 +2,12 @@ package utils_test
 
 import (
 	"drawbridge/pkg/utils"
+	"errors"
 	"github.com/stretchr/testify/require"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestBashCmdExec(t *testing.T) {
@@ -88,3 +91,39 @@ func TestCmdExec_WorkingDirAbsolute(t *testing.T) {
 	require.NoError(t, aerr)
 	require.NoError(t, cerr)
 }
+
+func TestParallelExec(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	var mutex sync.Mutex
+	running := 0
+	maxRunning := 0
+
+	//test
+	results := utils.ParallelExec([]string{"a", "b", "c", "d", "e"}, 2, func(item string) error {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+
+		if item == "c" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	//assert
+	require.Equal(t, 5, len(results), "should return a result for every item")
+	require.True(t, maxRunning <= 2, "should limit parallelism")
+	require.NoError(t, results[0])
+	require.Error(t, results[2], "should return errors in item order")
+}
@@ -3,8 +3,15 @@ package utils
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
 	"hash/fnv"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
 	"text/template"
+	"unicode"
 )
 
 
@@ -21,18 +28,63 @@ func PopulatePathTemplate(pathTmplContent string, data interface{}) (string, err
 }
 
 
-func PopulateTemplate(tmplContent string, data interface{}) (string, error) {
-	//set functions
-	fns := template.FuncMap{
+// TemplateFuncMap returns the functions available in every drawbridge template.
+// The value is always the last argument (like sprig), so functions can be used in pipelines: `{{.shard | upper}}`
+func TemplateFuncMap() template.FuncMap {
+	return template.FuncMap{
 		"uniquePort": UniquePort,
 		"expandPath": ExpandPath,
+
+		// strings
+		"lower":        strings.ToLower,
+		"upper":        strings.ToUpper,
+		"title":        tmplTitle,
+		"trim":         strings.TrimSpace,
+		"hasPrefix":    func(prefix string, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":    func(suffix string, s string) bool { return strings.HasSuffix(s, suffix) },
+		"replace":      func(old string, new string, s string) string { return strings.Replace(s, old, new, -1) },
+		"regexReplace": tmplRegexReplace,
+		"indent":       tmplIndent,
+
+		// lists
+		"join":  tmplJoin,
+		"split": func(sep string, s string) []string { return strings.Split(s, sep) },
+
+		// values
+		"default": tmplDefault,
+		"env":     os.Getenv,
+		"toJson":  tmplToJson,
+		"toYaml":  tmplToYaml,
 	}
+}
 
+func PopulateTemplate(tmplContent string, data interface{}) (string, error) {
+	return PopulateTemplateWithPartials(tmplContent, nil, nil, data)
+}
+
+// PopulateTemplateWithPartials populates the template content, which can include the partials with
+// `{{template "name" .}}`. The overrides are parsed after the content, their `{{define "name"}}` blocks replace the
+// partials & `{{block "name" .}}` blocks with the same name.
+func PopulateTemplateWithPartials(tmplContent string, partials map[string]string, overrides []string, data interface{}) (string, error) {
 	// prep the template, set the option
-	tmpl, err := template.New("populate").Option("missingkey=error").Funcs(fns).Parse(tmplContent)
+	tmpl := template.New("populate").Option("missingkey=error").Funcs(TemplateFuncMap())
+	for partialName, partialContent := range partials {
+		_, err := tmpl.New(partialName).Parse(partialContent)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	_, err := tmpl.Parse(tmplContent)
 	if err != nil {
 		return "", err
 	}
+	for _, override := range overrides {
+		_, err = tmpl.Parse(override)
+		if err != nil {
+			return "", err
+		}
+	}
 
 	//specify that any missing keys in the template will throw an error
 	buf := new(bytes.Buffer)
@@ -69,3 +121,94 @@ func UniquePort(data interface{}) (int, error) {
 	uniquePort := (hash.Sum32() % uint32(portRange)) + 1023
 	return int(uniquePort), nil
 }
+
+// tmplTitle capitalizes the first letter of every word (strings.Title is deprecated). Words are separated by anything
+// other than letters, digits & apostrophes, so `us-east-1` becomes `Us-East-1`.
+func tmplTitle(s string) string {
+	previous := ' '
+	return strings.Map(func(r rune) rune {
+		isSeparator := !unicode.IsLetter(previous) && !unicode.IsDigit(previous) && previous != '\''
+		previous = r
+		if isSeparator {
+			return unicode.ToTitle(r)
+		}
+		return r
+	}, s)
+}
+
+// tmplRegexReplace replaces every match of the regex, `$1` expands to the first submatch.
+func tmplRegexReplace(regex string, replacement string, s string) (string, error) {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(s, replacement), nil
+}
+
+// tmplIndent pads every line with spaces.
+func tmplIndent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+// tmplJoin accepts any list (eg. array answers).
+func tmplJoin(sep string, list interface{}) string {
+	listValue := reflect.ValueOf(list)
+	if listValue.Kind() != reflect.Slice && listValue.Kind() != reflect.Array {
+		return fmt.Sprintf("%v", list)
+	}
+
+	items := []string{}
+	for i := 0; i < listValue.Len(); i++ {
+		items = append(items, fmt.Sprintf("%v", listValue.Index(i).Interface()))
+	}
+	return strings.Join(items, sep)
+}
+
+// tmplDefault returns the defaultValue when the value is empty (nil, false, 0, "" or an empty list/map).
+func tmplDefault(defaultValue interface{}, value interface{}) interface{} {
+	if value == nil {
+		return defaultValue
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		if v.Len() == 0 {
+			return defaultValue
+		}
+	case reflect.Bool:
+		if !v.Bool() {
+			return defaultValue
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() == 0 {
+			return defaultValue
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() == 0 {
+			return defaultValue
+		}
+	case reflect.Float32, reflect.Float64:
+		if v.Float() == 0 {
+			return defaultValue
+		}
+	}
+	return value
+}
+
+func tmplToJson(value interface{}) (string, error) {
+	jsonData, err := json.Marshal(StringifyYAMLMapKeys(value))
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+func tmplToYaml(value interface{}) (string, error) {
+	yamlData, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(yamlData), "\n"), nil
+}
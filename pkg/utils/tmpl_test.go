@@ -146,3 +146,156 @@ func TestUniquePort_WithStringKey(t *testing.T) {
 	require.NoError(t, err, "should not raise an error")
 	require.Equal(t, 36016, port, "should generate repeatible unique port from data")
 }
+
+func TestPopulateTemplate_StringFuncs(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	data := map[string]interface{}{"shard": " US-East-1 ", "stack_name": "web app"}
+
+	//test
+	str, err := utils.PopulateTemplate(`{{.shard | trim | lower}} {{.stack_name | upper}} {{.stack_name | title}} {{.stack_name | replace " " "-"}}`, data)
+
+	//assert
+	require.NoError(t, err, "should not raise an error")
+	require.Equal(t, "us-east-1 WEB APP Web App web-app", str, "should support string case & replace functions in pipelines")
+}
+
+func TestPopulateTemplate_Title(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	data := map[string]interface{}{"region": "us-east-1", "name": "élan o'neil_db"}
+
+	//test
+	str, err := utils.PopulateTemplate(`{{.region | title}} {{.name | title}}`, data)
+
+	//assert
+	require.NoError(t, err, "should not raise an error")
+	require.Equal(t, "Us-East-1 Élan O'neil_Db", str, "should capitalize the first letter of every word")
+}
+
+func TestPopulateTemplate_RegexReplace(t *testing.T) {
+	t.Parallel()
+
+	//test
+	str, err := utils.PopulateTemplate(`{{.hostname | regexReplace "^ip-([0-9]+)-([0-9]+)$" "$1.$2"}}`, map[string]interface{}{"hostname": "ip-10-4"})
+
+	//assert
+	require.NoError(t, err, "should not raise an error")
+	require.Equal(t, "10.4", str, "should replace regex matches, and expand submatches")
+}
+
+func TestPopulateTemplate_RegexReplaceInvalid(t *testing.T) {
+	t.Parallel()
+
+	//test
+	_, err := utils.PopulateTemplate(`{{.hostname | regexReplace "(" ""}}`, map[string]interface{}{"hostname": "ip-10-4"})
+
+	//assert
+	require.Error(t, err, "should raise an error when the regex is invalid")
+}
+
+func TestPopulateTemplate_Default(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	data := map[string]interface{}{"username": nil, "shard": "", "port": 0, "services": []interface{}{}, "environment": "prod"}
+
+	//test
+	str, err := utils.PopulateTemplate(`{{.username | default "aws"}} {{.shard | default "us-east-1"}} {{.port | default 22}} {{.services | default "none"}} {{.environment | default "stage"}}`, data)
+
+	//assert
+	require.NoError(t, err, "should not raise an error")
+	require.Equal(t, "aws us-east-1 22 none prod", str, "should use the default value for empty values only")
+}
+
+func TestPopulateTemplate_JoinSplit(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	data := map[string]interface{}{"services": []interface{}{"web", "api", 8080}, "hosts": "bastion1,bastion2"}
+
+	//test
+	str, err := utils.PopulateTemplate(`{{.services | join ","}} {{range split "," .hosts}}[{{.}}]{{end}}`, data)
+
+	//assert
+	require.NoError(t, err, "should not raise an error")
+	require.Equal(t, "web,api,8080 [bastion1][bastion2]", str, "should join any list, and split strings into lists")
+}
+
+func TestPopulateTemplate_HasPrefix(t *testing.T) {
+	t.Parallel()
+
+	//test
+	str, err := utils.PopulateTemplate(`{{if hasPrefix "us-" .shard}}us{{else}}other{{end}}`, map[string]interface{}{"shard": "us-east-1"})
+
+	//assert
+	require.NoError(t, err, "should not raise an error")
+	require.Equal(t, "us", str, "should check string prefixes")
+}
+
+func TestPopulateTemplate_Env(t *testing.T) {
+	//setup
+	defer patchEnv("DRAWBRIDGE_TEST_USER", "jason")()
+
+	//test
+	str, err := utils.PopulateTemplate(`{{env "DRAWBRIDGE_TEST_USER"}}`, map[string]interface{}{})
+
+	//assert
+	require.NoError(t, err, "should not raise an error")
+	require.Equal(t, "jason", str, "should read environment variables")
+}
+
+func TestPopulateTemplate_ToJson(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	data := map[string]interface{}{"database": map[interface{}]interface{}{"host": "db1", "port": 5432}}
+
+	//test
+	str, err := utils.PopulateTemplate(`{{.database | toJson}}`, data)
+
+	//assert
+	require.NoError(t, err, "should not raise an error")
+	require.Equal(t, `{"host":"db1","port":5432}`, str, "should render values as json")
+}
+
+func TestPopulateTemplate_ToYamlIndent(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	data := map[string]interface{}{"database": map[string]interface{}{"host": "db1", "port": 5432}}
+
+	//test
+	str, err := utils.PopulateTemplate("database:\n{{.database | toYaml | indent 2}}", data)
+
+	//assert
+	require.NoError(t, err, "should not raise an error")
+	require.Equal(t, "database:\n  host: db1\n  port: 5432", str, "should render values as yaml, and indent every line")
+}
+
+func TestPopulateTemplateWithPartials(t *testing.T) {
+	t.Parallel()
+
+	//setup
+	partials := map[string]string{"globals": "ForwardAgent {{.forward}}"}
+	overrides := []string{`{{define "bastion"}}Host bastion.{{.shard}}{{end}}`}
+
+	//test
+	str, err := utils.PopulateTemplateWithPartials(`{{template "globals" .}} {{block "bastion" .}}Host bastion{{end}}`, partials, overrides, map[string]interface{}{"forward": "yes", "shard": "us-east-1"})
+
+	//assert
+	require.NoError(t, err, "should not raise an error")
+	require.Equal(t, "ForwardAgent yes Host bastion.us-east-1", str, "should include partials, and replace blocks with overrides")
+}
+
+func TestPopulateTemplateWithPartials_MissingPartial(t *testing.T) {
+	t.Parallel()
+
+	//test
+	_, err := utils.PopulateTemplateWithPartials(`{{template "missing" .}}`, map[string]string{}, nil, map[string]interface{}{})
+
+	//assert
+	require.Error(t, err, "should raise an error when the partial is not defined")
+}